@@ -1,12 +1,12 @@
 package archive
 
 import (
-	"os"
+	"io"
 
 	"github.com/bodgit/sevenzip"
 )
 
-func Walk7Zip(file *os.File, fileSize int64, walkFunc WalkFunc) error {
+func Walk7Zip(file io.ReaderAt, fileSize int64, walkFunc WalkFunc) error {
 	zfs, err := sevenzip.NewReader(file, fileSize)
 	if err != nil {
 		return err
@@ -1,7 +1,9 @@
 package archive
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
@@ -14,6 +16,9 @@ var (
 	ErrUnsupportedArchive = fmt.Errorf("unsupported archive")
 )
 
+// number of bytes that are needed in order to detect the archive type of a stream
+const peekSize = 3072
+
 // WalkFunc defines the function in order to efficiently walk over the archive
 type WalkFunc func(path string, info fs.FileInfo, r io.Reader, err error) error
 
@@ -42,22 +47,58 @@ func Walk(path string, walkcFunc WalkFunc) error {
 	switch mime.Extension() {
 	case ".7z":
 		return Walk7Zip(f, stat.Size(), walkcFunc)
-	case ".gz":
-		return WalkTarGzip(f, walkcFunc)
-	case ".tar":
-		return WalkTar(f, walkcFunc)
 	case ".zip":
 		return WalkZip(f, stat.Size(), walkcFunc)
+	}
+	return WalkReader(f, mime.Extension(), walkcFunc)
+}
+
+// DetectReader detects the archive type of a stream, e.g. stdin, by peeking at its first bytes.
+// The returned extension is empty in case the stream is no supported archive.
+// The returned reader yields all bytes of r, including the peeked ones.
+func DetectReader(r io.Reader) (extension string, rr io.Reader, err error) {
+	br := bufio.NewReaderSize(r, peekSize)
+	header, err := br.Peek(peekSize)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", nil, fmt.Errorf("could not read stream header: %w", err)
+	}
+
+	extension = mimetype.Detect(header).Extension()
+	switch extension {
+	case ".7z", ".gz", ".tar", ".zip", ".xz", ".zst", ".bz2", ".lz":
+		return extension, br, nil
+	}
+	return "", br, nil
+}
+
+// WalkReader walks over an archive stream with the given extension as returned by DetectReader.
+// Archives that require random access are buffered in memory.
+func WalkReader(r io.Reader, extension string, walkcFunc WalkFunc) error {
+	switch extension {
+	case ".7z", ".zip":
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("could not buffer archive: %w", err)
+		}
+		ra := bytes.NewReader(data)
+		if extension == ".7z" {
+			return Walk7Zip(ra, ra.Size(), walkcFunc)
+		}
+		return WalkZip(ra, ra.Size(), walkcFunc)
+	case ".gz":
+		return WalkTarGzip(r, walkcFunc)
+	case ".tar":
+		return WalkTar(r, walkcFunc)
 	case ".xz":
-		return WalkTarXz(f, walkcFunc)
+		return WalkTarXz(r, walkcFunc)
 	case ".zst":
-		return WalkTarZstd(f, walkcFunc)
+		return WalkTarZstd(r, walkcFunc)
 	case ".bz2":
-		return WalkTarBzip2(f, walkcFunc)
+		return WalkTarBzip2(r, walkcFunc)
 	case ".lz":
-		return WalkTarLz(f, walkcFunc)
+		return WalkTarLz(r, walkcFunc)
 	}
-	return fmt.Errorf("%w: %s", ErrUnsupportedArchive, mime.Extension())
+	return fmt.Errorf("%w: %s", ErrUnsupportedArchive, extension)
 }
 
 type File interface {
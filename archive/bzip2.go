@@ -2,10 +2,10 @@ package archive
 
 import (
 	"compress/bzip2"
-	"os"
+	"io"
 )
 
-func WalkTarBzip2(file *os.File, walkFunc WalkFunc) error {
+func WalkTarBzip2(file io.Reader, walkFunc WalkFunc) error {
 	r := bzip2.NewReader(file)
 	return WalkTar(r, walkFunc)
 }
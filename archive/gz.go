@@ -2,10 +2,10 @@ package archive
 
 import (
 	"compress/gzip"
-	"os"
+	"io"
 )
 
-func WalkTarGzip(file *os.File, walkFunc WalkFunc) error {
+func WalkTarGzip(file io.Reader, walkFunc WalkFunc) error {
 
 	r, err := gzip.NewReader(file)
 	if err != nil {
@@ -1,12 +1,12 @@
 package archive
 
 import (
-	"os"
+	"io"
 
 	"github.com/sorairolake/lzip-go"
 )
 
-func WalkTarLz(file *os.File, walkFunc WalkFunc) error {
+func WalkTarLz(file io.Reader, walkFunc WalkFunc) error {
 	r, err := lzip.NewReader(file)
 	if err != nil {
 		return err
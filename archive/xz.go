@@ -1,12 +1,12 @@
 package archive
 
 import (
-	"os"
+	"io"
 
 	"github.com/ulikunitz/xz"
 )
 
-func WalkTarXz(file *os.File, walkFunc WalkFunc) error {
+func WalkTarXz(file io.Reader, walkFunc WalkFunc) error {
 	r, err := xz.NewReader(file)
 	if err != nil {
 		return err
@@ -2,10 +2,10 @@ package archive
 
 import (
 	"archive/zip"
-	"os"
+	"io"
 )
 
-func WalkZip(file *os.File, fileSize int64, walkFunc WalkFunc) error {
+func WalkZip(file io.ReaderAt, fileSize int64, walkFunc WalkFunc) error {
 	zfs, err := zip.NewReader(file, fileSize)
 	if err != nil {
 		return err
@@ -1,12 +1,12 @@
 package archive
 
 import (
-	"os"
+	"io"
 
 	"github.com/klauspost/compress/zstd"
 )
 
-func WalkTarZstd(file *os.File, walkFunc WalkFunc) error {
+func WalkTarZstd(file io.Reader, walkFunc WalkFunc) error {
 	r, err := zstd.NewReader(file)
 	if err != nil {
 		return err
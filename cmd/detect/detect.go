@@ -0,0 +1,19 @@
+package detect
+
+import (
+	"github.com/jxsl13/twlog/internal/sharedcontext"
+	"github.com/spf13/cobra"
+)
+
+func NewDetectCommand(root *sharedcontext.Root) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "detect",
+		Short: "detect is the subcommand which allows to find suspicious activity without knowing what to search for",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	cmd.AddCommand(NewSpamCommand(root))
+	return cmd
+}
@@ -0,0 +1,134 @@
+package detect
+
+import (
+	"context"
+	"io"
+	"log"
+	"sync"
+
+	"github.com/jxsl13/cli-config-boilerplate/cliconfig"
+	"github.com/jxsl13/twlog/config"
+	"github.com/jxsl13/twlog/ctxutils"
+	"github.com/jxsl13/twlog/event"
+	"github.com/jxsl13/twlog/fswalk"
+	"github.com/jxsl13/twlog/internal/diag"
+	"github.com/jxsl13/twlog/internal/dialect"
+	"github.com/jxsl13/twlog/internal/filter"
+	"github.com/jxsl13/twlog/internal/playerstate"
+	"github.com/jxsl13/twlog/internal/sharedcontext"
+	"github.com/jxsl13/twlog/internal/spam"
+	"github.com/spf13/cobra"
+)
+
+func NewSpamCommand(root *sharedcontext.Root) *cobra.Command {
+	cli := &SpamContext{
+		root: root,
+		cfg:  config.NewSpamConfig(),
+	}
+
+	cmd := cobra.Command{
+		Use:   "spam",
+		Short: "spam finds chat messages that were sent from many different IPs within a short time and suggests a regex for who said",
+		Args:  cobra.NoArgs,
+	}
+	cmd.PreRunE = cli.PreRunE(&cmd)
+	cmd.RunE = cli.RunE
+	return &cmd
+}
+
+type SpamContext struct {
+	root *sharedcontext.Root
+	cfg  config.SpamConfig
+}
+
+func (cli *SpamContext) PreRunE(cmd *cobra.Command) func(*cobra.Command, []string) error {
+	parser := cliconfig.RegisterFlags(&cli.cfg, false, cmd, cliconfig.WithoutConfigFile())
+	return func(cmd *cobra.Command, args []string) error {
+		log.SetOutput(cmd.ErrOrStderr()) // redirect log output to stderr
+		return parser()
+	}
+}
+
+func (cli *SpamContext) RunE(cmd *cobra.Command, args []string) error {
+
+	var (
+		ctx      = cli.root.Ctx
+		mu       = &sync.Mutex{}
+		messages = make([]spam.Message, 0, 1024)
+		format   = cli.root.Format
+		states   = playerstate.NewStore()
+	)
+
+	err := fswalk.Walk(ctx, cli.root.Walk.ToFSWalkConfig(), func(filePath string, file io.Reader) error {
+		fileMessages, err := searchMessages(ctx, filePath, file, states.Players(file), cli.root.Filter.WhereExpr)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		messages = append(messages, fileMessages...)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = ctxutils.Done(ctx)
+	if err != nil {
+		return err
+	}
+
+	waves := spam.Detect(messages, cli.cfg.ToSpamConfig())
+	if cli.cfg.IPsOnly {
+		return format.Print(cmd, waves.ToIPTextList())
+	}
+	return format.Print(cmd, waves)
+}
+
+// searchMessages returns all chat messages of players whose IP is known that match the where expression.
+func searchMessages(ctx context.Context, filePath string, f io.Reader, playerMap map[int]string, where *filter.Expr) ([]spam.Message, error) {
+	messages := make([]spam.Message, 0, 64)
+	scanner := dialect.NewScanner(ctx, f)
+
+	var err error
+	for scanner.Scan() {
+		err = ctxutils.Done(ctx)
+		if err != nil {
+			return messages, err
+		}
+
+		switch e := scanner.Event().(type) {
+		case event.JoinEvent:
+			playerMap[e.ID] = e.IP
+		case event.LeaveEvent:
+			delete(playerMap, e.ID)
+		case event.ChatEvent:
+			if e.FromServer() {
+				continue
+			}
+
+			ip, ok := playerMap[e.ID]
+			if !ok {
+				diag.OrphanChat(ctx, filePath, e.LineNumber, e.Nickname, e.ID)
+				continue
+			}
+
+			if !where.Matches(filter.ChatRecord(filePath, e, ip)) {
+				continue
+			}
+
+			messages = append(messages, spam.Message{
+				File:       filePath,
+				LineNumber: e.LineNumber,
+				Time:       e.Time,
+				IP:         ip,
+				Nickname:   e.Nickname,
+				Text:       e.Text,
+			})
+		}
+	}
+
+	diag.ParseFailures(ctx, filePath, scanner.Malformed())
+
+	return messages, scanner.Err()
+}
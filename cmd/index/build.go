@@ -0,0 +1,101 @@
+package index
+
+import (
+	"errors"
+	"io"
+	"log"
+	"log/slog"
+	"slices"
+	"sync/atomic"
+
+	"github.com/jxsl13/cli-config-boilerplate/cliconfig"
+	"github.com/jxsl13/twlog/config"
+	"github.com/jxsl13/twlog/ctxutils"
+	"github.com/jxsl13/twlog/fswalk"
+	"github.com/jxsl13/twlog/internal/logindex"
+	"github.com/jxsl13/twlog/internal/sharedcontext"
+	"github.com/spf13/cobra"
+)
+
+func NewBuildCommand(root *sharedcontext.Root) *cobra.Command {
+	cli := &BuildContext{
+		root: root,
+		cfg:  config.NewIndexBuildConfig(),
+	}
+
+	cmd := cobra.Command{
+		Use:   "build",
+		Short: "build creates or updates the index, only log files and archives whose modification time or size changed are parsed again",
+		Args:  cobra.NoArgs,
+	}
+	cmd.PreRunE = cli.PreRunE(&cmd)
+	cmd.RunE = cli.RunE
+	return &cmd
+}
+
+type BuildContext struct {
+	root *sharedcontext.Root
+	cfg  config.IndexBuildConfig
+}
+
+func (cli *BuildContext) PreRunE(cmd *cobra.Command) func(*cobra.Command, []string) error {
+	parser := cliconfig.RegisterFlags(&cli.cfg, false, cmd, cliconfig.WithoutConfigFile())
+	return func(cmd *cobra.Command, args []string) error {
+		log.SetOutput(cmd.ErrOrStderr()) // redirect log output to stderr
+		return parser()
+	}
+}
+
+func (cli *BuildContext) RunE(cmd *cobra.Command, args []string) error {
+	var (
+		ctx     = cli.root.Ctx
+		indexed atomic.Int64
+	)
+
+	if slices.Contains(cli.root.Walk.Inputs, fswalk.Stdin) {
+		return errors.New("stdin cannot be indexed")
+	}
+
+	idx, err := logindex.Open(cli.root.Index.File, false)
+	if err != nil {
+		return err
+	}
+	defer idx.Close()
+
+	builder := logindex.NewBuilder(idx)
+	walkCfg := cli.root.Walk.ToFSWalkConfig()
+	walkCfg.Cache = builder
+
+	err = fswalk.Walk(ctx, walkCfg, func(filePath string, file io.Reader) error {
+		indexed.Add(1)
+		return builder.Index(ctx, filePath, file)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = ctxutils.Done(ctx)
+	if err != nil {
+		return err
+	}
+
+	pruned := 0
+	if cli.cfg.Prune {
+		removed, err := builder.Prune()
+		if err != nil {
+			return err
+		}
+		for _, path := range removed {
+			slog.Debug("removed from index", "path", path)
+		}
+		pruned = len(removed)
+	}
+
+	slog.Info("index updated",
+		"file", cli.root.Index.File,
+		"indexed", indexed.Load(),
+		"unchanged", builder.Skipped(),
+		"pruned", pruned,
+	)
+	return idx.Close()
+}
@@ -0,0 +1,84 @@
+package index
+
+import (
+	"fmt"
+
+	"github.com/jxsl13/twlog/cmd/what"
+	"github.com/jxsl13/twlog/cmd/who"
+	"github.com/jxsl13/twlog/internal/logindex"
+	"github.com/jxsl13/twlog/internal/sharedcontext"
+	"github.com/spf13/cobra"
+)
+
+func NewIndexCommand(root *sharedcontext.Root) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "index",
+		Short: "index is the subcommand which allows to parse the log files once and to search them repeatedly without decompressing archives",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	cmd.AddCommand(NewBuildCommand(root))
+	cmd.AddCommand(NewQueryCommand(root))
+	return cmd
+}
+
+func NewQueryCommand(root *sharedcontext.Root) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "query",
+		Short: "query searches the index with the same flags as the search commands, changed files are read instead",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	whoCmd := &cobra.Command{
+		Use:   "who",
+		Short: "who searches the index for players",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+	whoCmd.AddCommand(searchIndex(root, who.NewSaidCommand(root)))
+
+	whatCmd := &cobra.Command{
+		Use:   "what",
+		Short: "what searches the index for what players did",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+	whatCmd.AddCommand(searchIndex(root, what.NewSaidCommand(root)))
+
+	cmd.AddCommand(whoCmd, whatCmd)
+	return cmd
+}
+
+// contextFlags print the lines around a match, which are not indexed
+var contextFlags = []string{"before", "after", "context"}
+
+// searchIndex makes a search command walk the index instead of the log files.
+// The index is a snapshot, which is why the follow mode is not supported.
+func searchIndex(root *sharedcontext.Root, cmd *cobra.Command) *cobra.Command {
+	delete(cmd.Annotations, sharedcontext.AnnotationFollow)
+
+	run := cmd.RunE
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		for _, name := range contextFlags {
+			if f := cmd.Flags().Lookup(name); f != nil && f.Value.String() != "0" {
+				return fmt.Errorf("--%s is not supported by index query, the index only contains the lines of events", name)
+			}
+		}
+
+		idx, err := logindex.Open(root.Index.File, true)
+		if err != nil {
+			return err
+		}
+		defer idx.Close()
+
+		root.Walk.Cache = logindex.NewReader(idx)
+		return run(cmd, args)
+	}
+	return cmd
+}
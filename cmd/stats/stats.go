@@ -0,0 +1,259 @@
+package stats
+
+import (
+	"context"
+	"io"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/jxsl13/cli-config-boilerplate/cliconfig"
+	"github.com/jxsl13/twlog/config"
+	"github.com/jxsl13/twlog/ctxutils"
+	"github.com/jxsl13/twlog/event"
+	"github.com/jxsl13/twlog/fswalk"
+	"github.com/jxsl13/twlog/internal/diag"
+	"github.com/jxsl13/twlog/internal/dialect"
+	"github.com/jxsl13/twlog/internal/filter"
+	"github.com/jxsl13/twlog/internal/playerstate"
+	"github.com/jxsl13/twlog/internal/sharedcontext"
+	"github.com/jxsl13/twlog/model"
+	"github.com/jxsl13/twlog/stringutils"
+	"github.com/spf13/cobra"
+)
+
+func NewStatsCommand(root *sharedcontext.Root) *cobra.Command {
+	cli := &StatsContext{
+		root: root,
+		cfg:  config.NewStatsConfig(),
+	}
+
+	cmd := cobra.Command{
+		Use:   "stats",
+		Short: "stats aggregates the activity of all searched log files, e.g. the top chatters, IPs and phrases",
+		Args:  cobra.NoArgs,
+	}
+	cmd.PreRunE = cli.PreRunE(&cmd)
+	cmd.RunE = cli.RunE
+	return &cmd
+}
+
+type StatsContext struct {
+	root *sharedcontext.Root
+	cfg  config.StatsConfig
+}
+
+func (cli *StatsContext) PreRunE(cmd *cobra.Command) func(*cobra.Command, []string) error {
+	parser := cliconfig.RegisterFlags(&cli.cfg, false, cmd, cliconfig.WithoutConfigFile())
+	return func(cmd *cobra.Command, args []string) error {
+		log.SetOutput(cmd.ErrOrStderr()) // redirect log output to stderr
+		return parser()
+	}
+}
+
+func (cli *StatsContext) RunE(cmd *cobra.Command, args []string) error {
+
+	var (
+		ctx    = cli.root.Ctx
+		mu     = &sync.Mutex{}
+		total  = newCounter()
+		format = cli.root.Format
+		states = playerstate.NewStore()
+	)
+
+	err := fswalk.Walk(ctx, cli.root.Walk.ToFSWalkConfig(), func(filePath string, file io.Reader) error {
+		c, err := countFile(ctx, filePath, file, states.Players(file), cli.root.Filter.WhereExpr)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		total.Merge(c)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = ctxutils.Done(ctx)
+	if err != nil {
+		return err
+	}
+
+	return format.Print(cmd, total.StatList(cli.cfg.Top))
+}
+
+// countFile counts the joins and chat messages of a log file that match the where expression.
+// playerMap contains the client id -> IP state, which is needed in order to filter chat messages by IP.
+func countFile(ctx context.Context, filePath string, f io.Reader, playerMap map[int]string, where *filter.Expr) (*counter, error) {
+	c := newCounter()
+	scanner := dialect.NewScanner(ctx, f)
+
+	var err error
+	for scanner.Scan() {
+		err = ctxutils.Done(ctx)
+		if err != nil {
+			return c, err
+		}
+
+		switch e := scanner.Event().(type) {
+		case event.JoinEvent:
+			playerMap[e.ID] = e.IP
+			if where.Matches(filter.Record{File: filePath, Time: e.Time, IP: e.IP, Nicknames: []string{e.Nickname}}) {
+				c.Join(filePath, e)
+			}
+		case event.LeaveEvent:
+			delete(playerMap, e.ID)
+		case event.EnterEvent:
+			// DDNet join lines do not contain the nickname
+			ip, ok := playerMap[e.ID]
+			if ok && where.Matches(filter.Record{File: filePath, Time: e.Time, IP: ip, Nicknames: []string{e.Nickname}}) {
+				c.Nickname(e.Nickname, ip)
+			}
+		case event.ChatEvent:
+			if e.FromServer() {
+				continue
+			}
+			ip := playerMap[e.ID]
+			if where.Matches(filter.ChatRecord(filePath, e, ip)) {
+				c.Chat(filePath, e, ip)
+			}
+		}
+	}
+
+	diag.ParseFailures(ctx, filePath, scanner.Malformed())
+
+	return c, scanner.Err()
+}
+
+// counter aggregates the statistics of one or more log files.
+type counter struct {
+	messages     int
+	joins        int
+	chatters     map[string]int
+	ips          map[string]int
+	nicknames    map[string]map[string]bool // nickname -> IPs
+	phrases      map[string]int
+	hours        map[string]int
+	fileMessages map[string]int
+	fileJoins    map[string]int
+}
+
+func newCounter() *counter {
+	return &counter{
+		chatters:     make(map[string]int, 64),
+		ips:          make(map[string]int, 64),
+		nicknames:    make(map[string]map[string]bool, 64),
+		phrases:      make(map[string]int, 64),
+		hours:        make(map[string]int, 24),
+		fileMessages: make(map[string]int, 1),
+		fileJoins:    make(map[string]int, 1),
+	}
+}
+
+func (c *counter) Join(filePath string, e event.JoinEvent) {
+	c.joins++
+	c.ips[e.IP]++
+	c.fileJoins[filePath]++
+
+	c.Nickname(e.Nickname, e.IP)
+}
+
+// Nickname counts the IP of a client that used the nickname, e.g. in a join, enter or chat line.
+func (c *counter) Nickname(nickname, ip string) {
+	if nickname == "" || ip == "" {
+		return
+	}
+	c.addNickname(stringutils.VisualizeInvisible(nickname), ip)
+}
+
+// Chat counts a chat message, ip is the IP of the client and empty in case its join line is unknown.
+func (c *counter) Chat(filePath string, e event.ChatEvent, ip string) {
+	c.Nickname(e.Nickname, ip)
+	c.messages++
+	c.chatters[stringutils.VisualizeInvisible(e.Nickname)]++
+	c.fileMessages[filePath]++
+	if phrase := normalizePhrase(e.Text); phrase != "" {
+		c.phrases[phrase]++
+	}
+	if !e.Time.IsZero() {
+		c.hours[e.Time.Format("15")]++
+	}
+}
+
+func (c *counter) addNickname(nickname, ip string) {
+	ips, ok := c.nicknames[nickname]
+	if !ok {
+		ips = make(map[string]bool, 1)
+		c.nicknames[nickname] = ips
+	}
+	ips[ip] = true
+}
+
+// Merge adds all counts of o to c.
+func (c *counter) Merge(o *counter) {
+	c.messages += o.messages
+	c.joins += o.joins
+	mergeCounts(c.chatters, o.chatters)
+	mergeCounts(c.ips, o.ips)
+	mergeCounts(c.phrases, o.phrases)
+	mergeCounts(c.hours, o.hours)
+	mergeCounts(c.fileMessages, o.fileMessages)
+	mergeCounts(c.fileJoins, o.fileJoins)
+	for nickname, ips := range o.nicknames {
+		for ip := range ips {
+			c.addNickname(nickname, ip)
+		}
+	}
+}
+
+// StatList returns the totals followed by the top entries of every statistic.
+func (c *counter) StatList(top int) model.StatList {
+	// number of different IPs that used a nickname
+	nicknames := make(map[string]int, len(c.nicknames))
+	for nickname, ips := range c.nicknames {
+		nicknames[nickname] = len(ips)
+	}
+
+	files := make(map[string]bool, len(c.fileMessages)+len(c.fileJoins))
+	for file := range c.fileMessages {
+		files[file] = true
+	}
+	for file := range c.fileJoins {
+		files[file] = true
+	}
+
+	// totals keep their order
+	totals := model.StatList{
+		{Statistic: model.StatTotal, Key: "messages", Count: c.messages},
+		{Statistic: model.StatTotal, Key: "joins", Count: c.joins},
+		{Statistic: model.StatTotal, Key: "unique_ips", Count: len(c.ips)},
+		{Statistic: model.StatTotal, Key: "unique_chatters", Count: len(c.chatters)},
+		{Statistic: model.StatTotal, Key: "active_files", Count: len(files)},
+	}
+	for i := range totals {
+		totals[i].Rank = i + 1
+	}
+
+	l := make(model.StatList, 0, len(totals)+7*max(top, 16))
+	l = append(l, totals...)
+	l = append(l, model.NewStatList(model.StatChatters, c.chatters, top)...)
+	l = append(l, model.NewStatList(model.StatIPs, c.ips, top)...)
+	l = append(l, model.NewStatList(model.StatNicknameIPs, nicknames, top)...)
+	l = append(l, model.NewStatList(model.StatPhrases, c.phrases, top)...)
+	l = append(l, model.NewStatList(model.StatHours, c.hours, top)...)
+	l = append(l, model.NewStatList(model.StatFileMessages, c.fileMessages, top)...)
+	l = append(l, model.NewStatList(model.StatFileJoins, c.fileJoins, top)...)
+	return l
+}
+
+func mergeCounts(dst, src map[string]int) {
+	for key, count := range src {
+		dst[key] += count
+	}
+}
+
+// normalizePhrase makes chat lines that only differ in case or whitespace comparable.
+func normalizePhrase(text string) string {
+	return strings.ToLower(strings.Join(strings.Fields(stringutils.VisualizeInvisible(text)), " "))
+}
@@ -1,22 +1,25 @@
 package what
 
 import (
-	"bufio"
 	"context"
 	"errors"
 	"fmt"
 	"io"
 	"log"
-	"regexp"
+	"slices"
 	"sync"
 
 	"github.com/jxsl13/cli-config-boilerplate/cliconfig"
 	"github.com/jxsl13/twlog/config"
 	"github.com/jxsl13/twlog/ctxutils"
 	"github.com/jxsl13/twlog/fswalk"
+	"github.com/jxsl13/twlog/internal/chatsearch"
+	"github.com/jxsl13/twlog/internal/filter"
+	"github.com/jxsl13/twlog/internal/matcher"
+	"github.com/jxsl13/twlog/internal/playerstate"
 	"github.com/jxsl13/twlog/internal/sharedcontext"
 	"github.com/jxsl13/twlog/internal/sliceutils"
-	"github.com/jxsl13/twlog/match"
+	"github.com/jxsl13/twlog/internal/stream"
 	"github.com/jxsl13/twlog/model"
 	"github.com/spf13/cobra"
 )
@@ -28,7 +31,7 @@ func NewSaidCommand(root *sharedcontext.Root) *cobra.Command {
 	}
 
 	cmd := cobra.Command{
-		Use:   "said [nickname regex]",
+		Use:   "said [nickname regex]...",
 		Short: "said searches for what players said in the chat",
 	}
 	cmd.PreRunE = cli.PreRunE(&cmd)
@@ -39,7 +42,7 @@ func NewSaidCommand(root *sharedcontext.Root) *cobra.Command {
 type SaidContext struct {
 	root                 *sharedcontext.Root
 	cfg                  config.SaidConfig
-	NicknameSearchPhrase *regexp.Regexp
+	NicknameSearchPhrase matcher.Matcher
 }
 
 func (cli *SaidContext) PreRunE(cmd *cobra.Command) func(*cobra.Command, []string) error {
@@ -47,18 +50,23 @@ func (cli *SaidContext) PreRunE(cmd *cobra.Command) func(*cobra.Command, []strin
 	return func(cmd *cobra.Command, args []string) error {
 		log.SetOutput(cmd.ErrOrStderr()) // redirect log output to stderr
 
-		if len(args) == 0 {
-			return errors.New("missing search phrase regex argument")
+		err := parser()
+		if err != nil {
+			return err
+		}
+
+		cli.cfg.Patterns = slices.Concat(args, cli.cfg.Patterns)
+		if len(cli.cfg.Patterns) == 0 {
+			return errors.New("missing nickname regex argument or patterns file")
 		}
 
-		phrase, err := regexp.Compile(args[0])
+		phrase, err := matcher.New(cli.cfg.Patterns, cli.cfg.FixedStrings, cli.cfg.IgnoreCase)
 		if err != nil {
-			return fmt.Errorf("could not compile nickname search phrase regex: %w", err)
+			return fmt.Errorf("could not compile nickname search phrase: %w", err)
 		}
 
 		cli.NicknameSearchPhrase = phrase
-
-		return parser()
+		return nil
 	}
 }
 
@@ -69,10 +77,15 @@ func (cli *SaidContext) RunE(cmd *cobra.Command, args []string) error {
 		mu                 = &sync.Mutex{}
 		extendedPlayerList = make(model.PlayerExtendedList, 0, 64)
 		format             = cli.root.Format
+		states             = playerstate.NewStore()
 	)
 
+	if format.Stream {
+		return cli.stream(cmd)
+	}
+
 	err := fswalk.Walk(ctx, cli.root.Walk.ToFSWalkConfig(), func(filePath string, file io.Reader) error {
-		filePlayers, err := searchNicknamePhrase(ctx, filePath, file, cli.NicknameSearchPhrase)
+		filePlayers, err := searchNicknamePhrase(ctx, filePath, file, states.Players(file), cli.NicknameSearchPhrase, &cli.cfg, cli.root.Filter.WhereExpr)
 		if err != nil {
 			return err
 		}
@@ -112,50 +125,52 @@ func (cli *SaidContext) RunE(cmd *cobra.Command, args []string) error {
 	return format.Print(cmd, playerList)
 }
 
-func searchNicknamePhrase(ctx context.Context, filePath string, f io.Reader, nicknameRegexp *regexp.Regexp) (model.PlayerExtendedList, error) {
-
-	players := make(model.PlayerExtendedList, 0, 16)
+// stream prints the results of each file as soon as the file has been processed.
+func (cli *SaidContext) stream(cmd *cobra.Command) error {
+	var (
+		ctx     = cli.root.Ctx
+		format  = &cli.root.Format
+		walkCfg = cli.root.Walk.ToFSWalkConfig()
+		states  = playerstate.NewStore()
+	)
 
-	scanner := bufio.NewScanner(f)
-	scanner.Split(bufio.ScanLines)
+	search := func(filePath string, file io.Reader) (model.PlayerExtendedList, error) {
+		return searchNicknamePhrase(ctx, filePath, file, states.Players(file), cli.NicknameSearchPhrase, &cli.cfg, cli.root.Filter.WhereExpr)
+	}
 
-	// id -> ip
-	playerMap := make(map[int]string, 64)
 	var err error
-	for scanner.Scan() {
-		err = ctxutils.Done(ctx)
-		if err != nil {
-			return players, err
-		}
-
-		line := scanner.Text()
-
-		if id, ip, ok := match.Join(line); ok {
-			playerMap[id] = ip
-			continue
-		} else if id, ok := match.Leave(line); ok {
-			delete(playerMap, id)
-			continue
-		} else if id, nick, chat, ok := match.Chat(line); ok {
-			if !nicknameRegexp.MatchString(nick) {
-				continue
-			}
-
-			ip, ok := playerMap[id]
-			if !ok {
-				fmt.Printf("could not find join line for player %s with id: %d\n", nick, id)
-				continue
-			}
-
-			players = append(players, model.NewPlayerExtended(filePath, nick, id, ip, chat))
-		}
+	if cli.cfg.IPsOnly {
+		err = stream.Walk(ctx, cmd, format, walkCfg, cli.cfg.Deduplicate, func(filePath string, file io.Reader) ([]model.IPText, error) {
+			players, err := search(filePath, file)
+			return players.ToIPTextList(), err
+		})
+	} else if cli.cfg.Extended {
+		err = stream.Walk(ctx, cmd, format, walkCfg, cli.cfg.Deduplicate, func(filePath string, file io.Reader) ([]model.PlayerExtended, error) {
+			return search(filePath, file)
+		})
+	} else {
+		err = stream.Walk(ctx, cmd, format, walkCfg, cli.cfg.Deduplicate, func(filePath string, file io.Reader) ([]model.Player, error) {
+			players, err := search(filePath, file)
+			return players.ToPlayerList(), err
+		})
 	}
-
-	if err := scanner.Err(); err != nil {
-		if !errors.Is(err, io.EOF) {
-			return players, err
-		}
+	if err != nil {
+		return err
 	}
 
-	return players, nil
+	return ctxutils.Done(ctx)
+}
+
+// searchNicknamePhrase returns the chat messages of all players whose nickname matches one of the patterns.
+// playerMap contains the client id -> IP state, which may be carried over from previous log files.
+func searchNicknamePhrase(
+	ctx context.Context,
+	filePath string,
+	f io.Reader,
+	playerMap map[int]string,
+	nickname matcher.Matcher,
+	cfg *config.SaidConfig,
+	where *filter.Expr,
+) (model.PlayerExtendedList, error) {
+	return chatsearch.Search(ctx, filePath, f, playerMap, chatsearch.Nickname, nickname, cfg, where)
 }
@@ -0,0 +1,210 @@
+package who
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/netip"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/jxsl13/cli-config-boilerplate/cliconfig"
+	"github.com/jxsl13/twlog/config"
+	"github.com/jxsl13/twlog/ctxutils"
+	"github.com/jxsl13/twlog/event"
+	"github.com/jxsl13/twlog/fswalk"
+	"github.com/jxsl13/twlog/internal/diag"
+	"github.com/jxsl13/twlog/internal/dialect"
+	"github.com/jxsl13/twlog/internal/filter"
+	"github.com/jxsl13/twlog/internal/sharedcontext"
+	"github.com/jxsl13/twlog/model"
+	"github.com/spf13/cobra"
+)
+
+func NewAliasesCommand(root *sharedcontext.Root) *cobra.Command {
+	cli := &AliasesContext{
+		root: root,
+		cfg:  config.NewAliasesConfig(),
+	}
+
+	cmd := cobra.Command{
+		Use:   "aliases [nickname|ip|cidr]...",
+		Short: "aliases lists all nicknames and IP addresses that are connected to the given nicknames or IP addresses",
+	}
+	cmd.PreRunE = cli.PreRunE(&cmd)
+	cmd.RunE = cli.RunE
+	return &cmd
+}
+
+type AliasesContext struct {
+	root          *sharedcontext.Root
+	cfg           config.AliasesConfig
+	SeedPrefixes  []netip.Prefix
+	SeedNicknames []*regexp.Regexp
+}
+
+func (cli *AliasesContext) PreRunE(cmd *cobra.Command) func(*cobra.Command, []string) error {
+	parser := cliconfig.RegisterFlags(&cli.cfg, false, cmd, cliconfig.WithoutConfigFile())
+	return func(cmd *cobra.Command, args []string) error {
+		log.SetOutput(cmd.ErrOrStderr()) // redirect log output to stderr
+
+		if len(args) == 0 {
+			return errors.New("missing nickname or ip argument")
+		}
+
+		err := parser()
+		if err != nil {
+			return err
+		}
+
+		for _, arg := range args {
+			if prefix, err := config.ParsePrefix(arg); err == nil {
+				cli.SeedPrefixes = append(cli.SeedPrefixes, prefix)
+				continue
+			}
+
+			expr := arg
+			if !cli.cfg.Regex {
+				expr = "^" + regexp.QuoteMeta(arg) + "$"
+			}
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return fmt.Errorf("could not compile nickname regex: %w", err)
+			}
+			cli.SeedNicknames = append(cli.SeedNicknames, re)
+		}
+		return nil
+	}
+}
+
+func (cli *AliasesContext) RunE(cmd *cobra.Command, args []string) error {
+
+	var (
+		ctx    = cli.root.Ctx
+		mu     = &sync.Mutex{}
+		graph  = model.NewAliasGraph()
+		format = cli.root.Format
+	)
+
+	err := fswalk.Walk(ctx, cli.root.Walk.ToFSWalkConfig(), func(filePath string, file io.Reader) error {
+		observations, err := searchAliases(ctx, filePath, file, cli.root.Filter.WhereExpr)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, o := range observations {
+			graph.Observe(o.Nickname, o.IP, o.Time)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = ctxutils.Done(ctx)
+	if err != nil {
+		return err
+	}
+
+	component := graph.Component(cli.isSeed)
+	return format.Print(cmd, component)
+}
+
+func (cli *AliasesContext) isSeed(kind, value string) bool {
+	switch kind {
+	case model.AliasKindIP:
+		for _, prefix := range cli.SeedPrefixes {
+			if config.PrefixContains(prefix, value) {
+				return true
+			}
+		}
+	case model.AliasKindNickname:
+		for _, re := range cli.SeedNicknames {
+			if re.MatchString(value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// aliasObservation is a nickname that was used from an IP address at a specific time.
+type aliasObservation struct {
+	Nickname string
+	IP       string
+	Time     time.Time
+}
+
+func searchAliases(ctx context.Context, filePath string, f io.Reader, where *filter.Expr) ([]aliasObservation, error) {
+
+	observations := make([]aliasObservation, 0, 64)
+
+	scanner := dialect.NewScanner(ctx, f)
+
+	var (
+		// id -> player
+		playerMap = make(map[int]*connectedPlayer, 64)
+		err       error
+	)
+
+	observe := func(p *connectedPlayer, t time.Time) {
+		if !where.Matches(filter.Record{File: filePath, Time: t, IP: p.IP, Nicknames: []string{p.Nickname}}) {
+			return
+		}
+		observations = append(observations, aliasObservation{
+			Nickname: p.Nickname,
+			IP:       p.IP,
+			Time:     t,
+		})
+	}
+
+	rename := func(id int, nickname string, t time.Time) {
+		p, ok := playerMap[id]
+		if !ok {
+			return
+		}
+		p.Nickname = nickname
+		observe(p, t)
+	}
+
+	for scanner.Scan() {
+		err = ctxutils.Done(ctx)
+		if err != nil {
+			return observations, err
+		}
+
+		switch e := scanner.Event().(type) {
+		case event.JoinEvent:
+			p := &connectedPlayer{IP: e.IP, Nickname: e.Nickname}
+			playerMap[e.ID] = p
+			if p.Nickname == "" {
+				// DDNet announces the nickname after the join line
+				continue
+			}
+			observe(p, e.Time)
+		case event.EnterEvent:
+			rename(e.ID, e.Nickname, e.Time)
+		case event.TeamJoinEvent:
+			rename(e.ID, e.Nickname, e.Time)
+		case event.LeaveEvent:
+			delete(playerMap, e.ID)
+		case event.ChatEvent:
+			rename(e.ID, e.Nickname, e.Time)
+		case event.NameChangeEvent:
+			// the scanner resolves the client id, players may share the old name
+			rename(e.ID, e.NewName, e.Time)
+		}
+	}
+
+	diag.ParseFailures(ctx, filePath, scanner.Malformed())
+
+	if err := scanner.Err(); err != nil {
+		return observations, err
+	}
+
+	return observations, nil
+}
@@ -0,0 +1,25 @@
+package who
+
+import (
+	"github.com/jxsl13/twlog/config"
+	"github.com/jxsl13/twlog/internal/sharedcontext"
+	"github.com/jxsl13/twlog/model"
+	"github.com/spf13/cobra"
+)
+
+func NewBannedCommand(root *sharedcontext.Root) *cobra.Command {
+	cli := &ModerationContext{
+		root:    root,
+		cfg:     config.NewModerationConfig(),
+		actions: []string{model.ActionBan, model.ActionUnban},
+	}
+
+	cmd := cobra.Command{
+		Use:   "banned",
+		Short: "banned lists bans and unbans of IP addresses and who issued them",
+		Args:  cobra.NoArgs,
+	}
+	cmd.PreRunE = cli.PreRunE(&cmd)
+	cmd.RunE = cli.RunE
+	return &cmd
+}
@@ -0,0 +1,143 @@
+package who
+
+import (
+	"context"
+	"io"
+	"log"
+	"sync"
+
+	"github.com/jxsl13/cli-config-boilerplate/cliconfig"
+	"github.com/jxsl13/twlog/config"
+	"github.com/jxsl13/twlog/ctxutils"
+	"github.com/jxsl13/twlog/event"
+	"github.com/jxsl13/twlog/fswalk"
+	"github.com/jxsl13/twlog/internal/diag"
+	"github.com/jxsl13/twlog/internal/dialect"
+	"github.com/jxsl13/twlog/internal/filter"
+	"github.com/jxsl13/twlog/internal/sharedcontext"
+	"github.com/jxsl13/twlog/model"
+	"github.com/spf13/cobra"
+)
+
+func NewJoinedCommand(root *sharedcontext.Root) *cobra.Command {
+	cli := &JoinedContext{
+		root: root,
+		cfg:  config.NewJoinedConfig(),
+	}
+
+	cmd := cobra.Command{
+		Use:   "joined",
+		Short: "joined lists players that joined the server, including those that never said anything",
+		Args:  cobra.NoArgs,
+	}
+	cmd.PreRunE = cli.PreRunE(&cmd)
+	cmd.RunE = cli.RunE
+	return &cmd
+}
+
+type JoinedContext struct {
+	root *sharedcontext.Root
+	cfg  config.JoinedConfig
+}
+
+func (cli *JoinedContext) PreRunE(cmd *cobra.Command) func(*cobra.Command, []string) error {
+	parser := cliconfig.RegisterFlags(&cli.cfg, false, cmd, cliconfig.WithoutConfigFile())
+	return func(cmd *cobra.Command, args []string) error {
+		log.SetOutput(cmd.ErrOrStderr()) // redirect log output to stderr
+		return parser()
+	}
+}
+
+func (cli *JoinedContext) RunE(cmd *cobra.Command, args []string) error {
+
+	var (
+		ctx      = cli.root.Ctx
+		mu       = &sync.Mutex{}
+		joinList = make(model.JoinList, 0, 64)
+		format   = cli.root.Format
+	)
+
+	err := fswalk.Walk(ctx, cli.root.Walk.ToFSWalkConfig(), func(filePath string, file io.Reader) error {
+		fileJoins, err := searchJoins(ctx, filePath, file, &cli.cfg, cli.root.Filter.WhereExpr)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		joinList = append(joinList, fileJoins...)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = ctxutils.Done(ctx)
+	if err != nil {
+		return err
+	}
+
+	return format.Print(cmd, joinList)
+}
+
+func searchJoins(ctx context.Context, filePath string, f io.Reader, cfg *config.JoinedConfig, where *filter.Expr) (model.JoinList, error) {
+
+	scanner := dialect.NewScanner(ctx, f)
+
+	var (
+		// all joins in the order they were logged
+		joins = make([]*event.JoinEvent, 0, 16)
+		// id -> client version, DDNet logs the version before the join line
+		versionMap = make(map[int]int, 64)
+		// id -> join without a nickname, DDNet announces the nickname after the join line
+		unnamed = make(map[int]*event.JoinEvent, 4)
+		err     error
+	)
+
+	for scanner.Scan() {
+		err = ctxutils.Done(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		switch e := scanner.Event().(type) {
+		case event.ClientVersionEvent:
+			versionMap[e.ID] = e.Version
+		case event.JoinEvent:
+			if e.Version == 0 {
+				e.Version = versionMap[e.ID]
+			}
+			joins = append(joins, &e)
+			delete(unnamed, e.ID)
+			if e.Nickname == "" {
+				unnamed[e.ID] = &e
+			}
+		case event.EnterEvent:
+			// the scanner resolves the client id of the enter line
+			if j, ok := unnamed[e.ID]; ok {
+				j.Nickname = e.Nickname
+				delete(unnamed, e.ID)
+			}
+		case event.LeaveEvent:
+			delete(versionMap, e.ID)
+			delete(unnamed, e.ID)
+		}
+	}
+
+	diag.ParseFailures(ctx, filePath, scanner.Malformed())
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	joinList := make(model.JoinList, 0, len(joins))
+	for _, e := range joins {
+		if !cfg.Matches(e.IP, e.Version, e.Nickname, e.Clan, e.Country) {
+			continue
+		}
+		if !where.Matches(filter.Record{File: filePath, Time: e.Time, IP: e.IP, Nicknames: []string{e.Nickname}}) {
+			continue
+		}
+		joinList = append(joinList, model.NewJoin(filePath, e.Time, e.ID, e.IP, e.Port, e.Version, e.Nickname, e.Clan, e.Country))
+	}
+	return joinList, nil
+}
@@ -0,0 +1,25 @@
+package who
+
+import (
+	"github.com/jxsl13/twlog/config"
+	"github.com/jxsl13/twlog/internal/sharedcontext"
+	"github.com/jxsl13/twlog/model"
+	"github.com/spf13/cobra"
+)
+
+func NewKickedCommand(root *sharedcontext.Root) *cobra.Command {
+	cli := &ModerationContext{
+		root:    root,
+		cfg:     config.NewModerationConfig(),
+		actions: []string{model.ActionKick},
+	}
+
+	cmd := cobra.Command{
+		Use:   "kicked",
+		Short: "kicked lists players that were kicked from the server and who kicked them",
+		Args:  cobra.NoArgs,
+	}
+	cmd.PreRunE = cli.PreRunE(&cmd)
+	cmd.RunE = cli.RunE
+	return &cmd
+}
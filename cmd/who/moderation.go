@@ -0,0 +1,214 @@
+package who
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jxsl13/cli-config-boilerplate/cliconfig"
+	"github.com/jxsl13/twlog/config"
+	"github.com/jxsl13/twlog/ctxutils"
+	"github.com/jxsl13/twlog/event"
+	"github.com/jxsl13/twlog/fswalk"
+	"github.com/jxsl13/twlog/internal/diag"
+	"github.com/jxsl13/twlog/internal/dialect"
+	"github.com/jxsl13/twlog/internal/filter"
+	"github.com/jxsl13/twlog/internal/sharedcontext"
+	"github.com/jxsl13/twlog/model"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// maximum time between an rcon command and the resulting kick or ban
+	rconAttributionWindow = 5 * time.Second
+	// maximum time between a kick vote being called and the resulting kick or ban
+	voteAttributionWindow = time.Minute
+)
+
+// ModerationContext is shared by the kicked and banned commands.
+type ModerationContext struct {
+	root    *sharedcontext.Root
+	cfg     config.ModerationConfig
+	actions []string
+}
+
+func (cli *ModerationContext) PreRunE(cmd *cobra.Command) func(*cobra.Command, []string) error {
+	parser := cliconfig.RegisterFlags(&cli.cfg, false, cmd, cliconfig.WithoutConfigFile())
+	return func(cmd *cobra.Command, args []string) error {
+		log.SetOutput(cmd.ErrOrStderr()) // redirect log output to stderr
+		return parser()
+	}
+}
+
+func (cli *ModerationContext) RunE(cmd *cobra.Command, args []string) error {
+
+	var (
+		ctx            = cli.root.Ctx
+		mu             = &sync.Mutex{}
+		moderationList = make(model.ModerationList, 0, 64)
+		format         = cli.root.Format
+	)
+
+	err := fswalk.Walk(ctx, cli.root.Walk.ToFSWalkConfig(), func(filePath string, file io.Reader) error {
+		fileModerations, err := searchModeration(ctx, filePath, file, &cli.cfg, cli.actions, cli.root.Filter.WhereExpr)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		moderationList = append(moderationList, fileModerations...)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = ctxutils.Done(ctx)
+	if err != nil {
+		return err
+	}
+
+	return format.Print(cmd, moderationList)
+}
+
+type connectedPlayer struct {
+	IP       string
+	Nickname string
+}
+
+func searchModeration(ctx context.Context, filePath string, f io.Reader, cfg *config.ModerationConfig, actions []string, where *filter.Expr) (model.ModerationList, error) {
+
+	moderations := make(model.ModerationList, 0, 16)
+
+	scanner := dialect.NewScanner(ctx, f)
+
+	var (
+		// id -> player
+		playerMap = make(map[int]*connectedPlayer, 64)
+		// ip -> last known nickname, also contains players that already left
+		nicknameMap = make(map[string]string, 64)
+
+		lastRcon *event.RconEvent
+		lastVote *event.VoteKickEvent
+	)
+
+	// triggeredBy returns the admin or vote that caused a kick or ban of the player with the given nickname.
+	triggeredBy := func(t time.Time, nickname string, commands ...string) string {
+		if lastRcon != nil && withinWindow(lastRcon.Time, t, rconAttributionWindow) {
+			command, _, _ := strings.Cut(strings.TrimSpace(lastRcon.Command), " ")
+			for _, c := range commands {
+				if strings.EqualFold(command, c) {
+					by := fmt.Sprintf("rcon id=%d", lastRcon.ID)
+					if p, ok := playerMap[lastRcon.ID]; ok && p.Nickname != "" {
+						by = fmt.Sprintf("rcon id=%d name=%s", lastRcon.ID, p.Nickname)
+					}
+					lastRcon = nil
+					return by
+				}
+			}
+		}
+
+		if lastVote != nil && lastVote.Target == nickname && withinWindow(lastVote.Time, t, voteAttributionWindow) {
+			by := fmt.Sprintf("vote called by %s (%s)", lastVote.Caller, lastVote.Reason)
+			lastVote = nil
+			return by
+		}
+		return ""
+	}
+
+	add := func(m model.Moderation) {
+		// the reason is the text of a moderation
+		if !where.Matches(filter.Record{File: m.File, Time: m.Time, IP: m.IP, Nicknames: []string{m.Nickname}, Text: m.Reason}) {
+			return
+		}
+		for _, action := range actions {
+			if m.Action == action && cfg.Matches(m.IP, m.Nickname) {
+				moderations = append(moderations, m)
+				return
+			}
+		}
+	}
+
+	rename := func(id int, nickname string) {
+		if p, ok := playerMap[id]; ok {
+			p.Nickname = nickname
+			nicknameMap[p.IP] = nickname
+		}
+	}
+
+	var err error
+	for scanner.Scan() {
+		err = ctxutils.Done(ctx)
+		if err != nil {
+			return moderations, err
+		}
+
+		switch e := scanner.Event().(type) {
+		case event.JoinEvent:
+			playerMap[e.ID] = &connectedPlayer{IP: e.IP}
+		case event.EnterEvent:
+			rename(e.ID, e.Nickname)
+		case event.TeamJoinEvent:
+			rename(e.ID, e.Nickname)
+		case event.ChatEvent:
+			rename(e.ID, e.Nickname)
+		case event.NameChangeEvent:
+			// the scanner resolves the client id, players may share the old name
+			rename(e.ID, e.NewName)
+		case event.RconEvent:
+			lastRcon = &e
+		case event.VoteKickEvent:
+			lastVote = &e
+		case event.LeaveEvent:
+			p, ok := playerMap[e.ID]
+			delete(playerMap, e.ID)
+			if !e.Kicked() {
+				continue
+			}
+
+			ip := e.IP
+			nickname := nicknameMap[ip]
+			if ok {
+				nickname = p.Nickname
+			}
+
+			m := model.NewModeration(filePath, e.Time, model.ActionKick, ip, nickname, e.Reason)
+			m.By = triggeredBy(e.Time, nickname, "kick")
+			add(m)
+		case event.BanEvent:
+			nickname := nicknameMap[e.Target]
+			m := model.NewModeration(filePath, e.Time, model.ActionBan, e.Target, nickname, e.Reason)
+			m.Duration = model.Duration(e.Duration)
+			m.Permanent = e.Permanent
+			m.By = triggeredBy(e.Time, nickname, "ban", "ban_range", "ban_region", "ban_region_range")
+			add(m)
+		case event.UnbanEvent:
+			nickname := nicknameMap[e.Target]
+			m := model.NewModeration(filePath, e.Time, model.ActionUnban, e.Target, nickname, "")
+			m.By = triggeredBy(e.Time, nickname, "unban", "unban_range", "unban_all")
+			add(m)
+		}
+	}
+
+	diag.ParseFailures(ctx, filePath, scanner.Malformed())
+
+	if err := scanner.Err(); err != nil {
+		return moderations, err
+	}
+
+	return moderations, nil
+}
+
+// withinWindow reports whether b happened at most window after a.
+// Lines without timestamps are assumed to be close to each other.
+func withinWindow(a, b time.Time, window time.Duration) bool {
+	if a.IsZero() || b.IsZero() {
+		return true
+	}
+	d := b.Sub(a)
+	return d >= 0 && d <= window
+}
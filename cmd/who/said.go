@@ -1,22 +1,25 @@
 package who
 
 import (
-	"bufio"
 	"context"
 	"errors"
 	"fmt"
 	"io"
 	"log"
-	"regexp"
+	"slices"
 	"sync"
 
 	"github.com/jxsl13/cli-config-boilerplate/cliconfig"
 	"github.com/jxsl13/twlog/config"
 	"github.com/jxsl13/twlog/ctxutils"
 	"github.com/jxsl13/twlog/fswalk"
+	"github.com/jxsl13/twlog/internal/chatsearch"
+	"github.com/jxsl13/twlog/internal/filter"
+	"github.com/jxsl13/twlog/internal/matcher"
+	"github.com/jxsl13/twlog/internal/playerstate"
 	"github.com/jxsl13/twlog/internal/sharedcontext"
 	"github.com/jxsl13/twlog/internal/sliceutils"
-	"github.com/jxsl13/twlog/match"
+	"github.com/jxsl13/twlog/internal/stream"
 	"github.com/jxsl13/twlog/model"
 	"github.com/spf13/cobra"
 )
@@ -28,8 +31,11 @@ func NewSaidCommand(root *sharedcontext.Root) *cobra.Command {
 	}
 
 	cmd := cobra.Command{
-		Use:   "said [text regex]",
+		Use:   "said [text regex]...",
 		Short: "said searches for what players said in the chat",
+		Annotations: map[string]string{
+			sharedcontext.AnnotationFollow: "true",
+		},
 	}
 	cmd.PreRunE = cli.PreRunE(&cmd)
 	cmd.RunE = cli.RunE
@@ -37,9 +43,9 @@ func NewSaidCommand(root *sharedcontext.Root) *cobra.Command {
 }
 
 type SaidContext struct {
-	root               *sharedcontext.Root
-	cfg                config.SaidConfig
-	SearchPhraseRegexp *regexp.Regexp
+	root         *sharedcontext.Root
+	cfg          config.SaidConfig
+	SearchPhrase matcher.Matcher
 }
 
 func (cli *SaidContext) PreRunE(cmd *cobra.Command) func(*cobra.Command, []string) error {
@@ -47,18 +53,23 @@ func (cli *SaidContext) PreRunE(cmd *cobra.Command) func(*cobra.Command, []strin
 	return func(cmd *cobra.Command, args []string) error {
 		log.SetOutput(cmd.ErrOrStderr()) // redirect log output to stderr
 
-		if len(args) == 0 {
-			return errors.New("missing search phrase regex argument")
+		err := parser()
+		if err != nil {
+			return err
 		}
 
-		phrase, err := regexp.Compile(args[0])
-		if err != nil {
-			return fmt.Errorf("could not compile search phrase regex: %w", err)
+		cli.cfg.Patterns = slices.Concat(args, cli.cfg.Patterns)
+		if len(cli.cfg.Patterns) == 0 {
+			return errors.New("missing search phrase regex argument or patterns file")
 		}
 
-		cli.SearchPhraseRegexp = phrase
+		phrase, err := matcher.New(cli.cfg.Patterns, cli.cfg.FixedStrings, cli.cfg.IgnoreCase)
+		if err != nil {
+			return fmt.Errorf("could not compile search phrase: %w", err)
+		}
 
-		return parser()
+		cli.SearchPhrase = phrase
+		return nil
 	}
 }
 
@@ -69,10 +80,17 @@ func (cli *SaidContext) RunE(cmd *cobra.Command, args []string) error {
 		mu                 = &sync.Mutex{}
 		extendedPlayerList = make(model.PlayerExtendedList, 0, 64)
 		format             = cli.root.Format
+		states             = playerstate.NewStore()
 	)
 
+	if cli.root.Walk.Follow {
+		return cli.follow(cmd)
+	} else if format.Stream {
+		return cli.stream(cmd)
+	}
+
 	err := fswalk.Walk(ctx, cli.root.Walk.ToFSWalkConfig(), func(filePath string, file io.Reader) error {
-		filePlayers, err := searchPhrase(ctx, filePath, file, cli.SearchPhraseRegexp)
+		filePlayers, err := searchPhrase(ctx, filePath, file, states.Players(file), cli.SearchPhrase, &cli.cfg, cli.root.Filter.WhereExpr)
 		if err != nil {
 			return err
 		}
@@ -112,51 +130,80 @@ func (cli *SaidContext) RunE(cmd *cobra.Command, args []string) error {
 	return format.Print(cmd, playerList)
 }
 
-func searchPhrase(ctx context.Context, filePath string, f io.Reader, phraseRegexp *regexp.Regexp) (model.PlayerExtendedList, error) {
-
-	players := make(model.PlayerExtendedList, 0, 16)
+// stream prints the results of each file as soon as the file has been processed.
+func (cli *SaidContext) stream(cmd *cobra.Command) error {
+	var (
+		ctx     = cli.root.Ctx
+		format  = &cli.root.Format
+		walkCfg = cli.root.Walk.ToFSWalkConfig()
+		states  = playerstate.NewStore()
+	)
 
-	scanner := bufio.NewScanner(f)
-	scanner.Split(bufio.ScanLines)
+	search := func(filePath string, file io.Reader) (model.PlayerExtendedList, error) {
+		return searchPhrase(ctx, filePath, file, states.Players(file), cli.SearchPhrase, &cli.cfg, cli.root.Filter.WhereExpr)
+	}
 
-	// id -> ip
-	playerMap := make(map[int]string, 64)
 	var err error
-	for scanner.Scan() {
-		err = ctxutils.Done(ctx)
-		if err != nil {
-			return players, err
-		}
-
-		line := scanner.Text()
-
-		if id, ip, ok := match.Join(line); ok {
-			playerMap[id] = ip
-			continue
-		} else if id, ok := match.Leave(line); ok {
-			delete(playerMap, id)
-			continue
-		} else if id, nick, chat, ok := match.Chat(line); ok {
-			if !phraseRegexp.MatchString(chat) {
-				continue
-			}
+	if cli.cfg.IPsOnly {
+		err = stream.Walk(ctx, cmd, format, walkCfg, cli.cfg.Deduplicate, func(filePath string, file io.Reader) ([]string, error) {
+			players, err := search(filePath, file)
+			return players.ToIPList(), err
+		})
+	} else if cli.cfg.Extended {
+		err = stream.Walk(ctx, cmd, format, walkCfg, cli.cfg.Deduplicate, func(filePath string, file io.Reader) ([]model.PlayerExtended, error) {
+			return search(filePath, file)
+		})
+	} else {
+		err = stream.Walk(ctx, cmd, format, walkCfg, cli.cfg.Deduplicate, func(filePath string, file io.Reader) ([]model.Player, error) {
+			players, err := search(filePath, file)
+			return players.ToPlayerList(), err
+		})
+	}
+	if err != nil {
+		return err
+	}
 
-			ip, ok := playerMap[id]
-			if !ok {
-				fmt.Printf("could not find join line for player %s with id: %d\n", nick, id)
-				continue
-			}
+	return ctxutils.Done(ctx)
+}
 
-			players = append(players, model.NewPlayerExtended(filePath, nick, id, ip, chat))
+// follow prints the results of all files as soon as new chat messages are appended to them.
+func (cli *SaidContext) follow(cmd *cobra.Command) error {
+	var (
+		ctx     = cli.root.Ctx
+		format  = &cli.root.Format
+		walkCfg = cli.root.Walk.ToFSWalkConfig()
+	)
 
-		}
+	scan := func(filePath string, file io.Reader, emit func(...model.PlayerExtended) error) error {
+		// followed files keep their state across rotations
+		return chatsearch.Scan(ctx, filePath, file, make(map[int]string, 64), chatsearch.Text, cli.SearchPhrase, &cli.cfg, cli.root.Filter.WhereExpr, emit)
 	}
 
-	if err := scanner.Err(); err != nil {
-		if !errors.Is(err, io.EOF) {
-			return players, err
-		}
+	if cli.cfg.IPsOnly {
+		return stream.Follow(ctx, cmd, format, walkCfg, cli.cfg.Deduplicate, func(filePath string, file io.Reader, emit func(...string) error) error {
+			return scan(filePath, file, func(p ...model.PlayerExtended) error {
+				return emit(model.PlayerExtendedList(p).ToIPList()...)
+			})
+		})
+	} else if cli.cfg.Extended {
+		return stream.Follow(ctx, cmd, format, walkCfg, cli.cfg.Deduplicate, scan)
 	}
+	return stream.Follow(ctx, cmd, format, walkCfg, cli.cfg.Deduplicate, func(filePath string, file io.Reader, emit func(...model.Player) error) error {
+		return scan(filePath, file, func(p ...model.PlayerExtended) error {
+			return emit(model.PlayerExtendedList(p).ToPlayerList()...)
+		})
+	})
+}
 
-	return players, nil
+// searchPhrase returns the chat messages that match one of the phrase patterns.
+func searchPhrase(
+	ctx context.Context,
+	filePath string,
+	f io.Reader,
+	playerMap map[int]string,
+	phrase matcher.Matcher,
+	cfg *config.SaidConfig,
+	where *filter.Expr,
+) (model.PlayerExtendedList, error) {
+	return chatsearch.Search(ctx, filePath, f, playerMap, chatsearch.Text, phrase, cfg, where)
 }
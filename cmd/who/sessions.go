@@ -0,0 +1,157 @@
+package who
+
+import (
+	"context"
+	"io"
+	"log"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/jxsl13/cli-config-boilerplate/cliconfig"
+	"github.com/jxsl13/twlog/config"
+	"github.com/jxsl13/twlog/ctxutils"
+	"github.com/jxsl13/twlog/event"
+	"github.com/jxsl13/twlog/fswalk"
+	"github.com/jxsl13/twlog/internal/diag"
+	"github.com/jxsl13/twlog/internal/dialect"
+	"github.com/jxsl13/twlog/internal/filter"
+	"github.com/jxsl13/twlog/internal/sharedcontext"
+	"github.com/jxsl13/twlog/model"
+	"github.com/spf13/cobra"
+)
+
+func NewSessionsCommand(root *sharedcontext.Root) *cobra.Command {
+	cli := &SessionsContext{
+		root: root,
+		cfg:  config.NewSessionsConfig(),
+	}
+
+	cmd := cobra.Command{
+		Use:   "sessions",
+		Short: "sessions lists when players connected to and disconnected from the server",
+		Args:  cobra.NoArgs,
+	}
+	cmd.PreRunE = cli.PreRunE(&cmd)
+	cmd.RunE = cli.RunE
+	return &cmd
+}
+
+type SessionsContext struct {
+	root *sharedcontext.Root
+	cfg  config.SessionsConfig
+}
+
+func (cli *SessionsContext) PreRunE(cmd *cobra.Command) func(*cobra.Command, []string) error {
+	parser := cliconfig.RegisterFlags(&cli.cfg, false, cmd, cliconfig.WithoutConfigFile())
+	return func(cmd *cobra.Command, args []string) error {
+		log.SetOutput(cmd.ErrOrStderr()) // redirect log output to stderr
+		return parser()
+	}
+}
+
+func (cli *SessionsContext) RunE(cmd *cobra.Command, args []string) error {
+
+	var (
+		ctx         = cli.root.Ctx
+		mu          = &sync.Mutex{}
+		sessionList = make(model.SessionList, 0, 64)
+		format      = cli.root.Format
+	)
+
+	err := fswalk.Walk(ctx, cli.root.Walk.ToFSWalkConfig(), func(filePath string, file io.Reader) error {
+		fileSessions, err := searchSessions(ctx, filePath, file, &cli.cfg, cli.root.Filter.WhereExpr)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		sessionList = append(sessionList, fileSessions...)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = ctxutils.Done(ctx)
+	if err != nil {
+		return err
+	}
+
+	return format.Print(cmd, sessionList)
+}
+
+func searchSessions(ctx context.Context, filePath string, f io.Reader, cfg *config.SessionsConfig, where *filter.Expr) (model.SessionList, error) {
+
+	sessions := make(model.SessionList, 0, 16)
+
+	scanner := dialect.NewScanner(ctx, f)
+
+	// id -> open session
+	openSessions := make(map[int]*model.Session, 64)
+	closeSession := func(s *model.Session) {
+		delete(openSessions, s.ID)
+		if cfg.Matches(s.IP, s.Nicknames) && where.Matches(filter.Record{File: filePath, Time: s.JoinTime, IP: s.IP, Nicknames: s.Nicknames}) {
+			sessions = append(sessions, *s)
+		}
+	}
+
+	var err error
+	for scanner.Scan() {
+		err = ctxutils.Done(ctx)
+		if err != nil {
+			return sessions, err
+		}
+
+		switch e := scanner.Event().(type) {
+		case event.JoinEvent:
+			if s, ok := openSessions[e.ID]; ok {
+				// missed the leave line, the end of the previous session is unknown
+				closeSession(s)
+			}
+			s := model.NewSession(filePath, e.ID, e.IP, e.Time)
+			if e.Nickname != "" {
+				s.AddNickname(e.Nickname)
+			}
+			openSessions[e.ID] = s
+		case event.LeaveEvent:
+			s, ok := openSessions[e.ID]
+			if !ok {
+				continue
+			}
+			s.Close(e.Time, e.Reason)
+			closeSession(s)
+		case event.EnterEvent:
+			// DDNet players that never chat are only known by the enter line
+			if s, ok := openSessions[e.ID]; ok {
+				s.AddNickname(e.Nickname)
+			}
+		case event.TeamJoinEvent:
+			if s, ok := openSessions[e.ID]; ok {
+				s.AddNickname(e.Nickname)
+			}
+		case event.ChatEvent:
+			if s, ok := openSessions[e.ID]; ok {
+				s.AddNickname(e.Nickname)
+			}
+		case event.NameChangeEvent:
+			// the scanner resolves the client id, players may share the old name
+			if s, ok := openSessions[e.ID]; ok {
+				s.AddNickname(e.NewName)
+			}
+		}
+	}
+
+	diag.ParseFailures(ctx, filePath, scanner.Malformed())
+
+	if err := scanner.Err(); err != nil {
+		return sessions, err
+	}
+
+	// sessions that were still open when the log file ended
+	for _, id := range slices.Sorted(maps.Keys(openSessions)) {
+		closeSession(openSessions[id])
+	}
+
+	return sessions, nil
+}
@@ -15,5 +15,10 @@ func NewWhoCommand(root *sharedcontext.Root) *cobra.Command {
 	}
 
 	cmd.AddCommand(NewSaidCommand(root))
+	cmd.AddCommand(NewJoinedCommand(root))
+	cmd.AddCommand(NewSessionsCommand(root))
+	cmd.AddCommand(NewKickedCommand(root))
+	cmd.AddCommand(NewBannedCommand(root))
+	cmd.AddCommand(NewAliasesCommand(root))
 	return cmd
 }
@@ -0,0 +1,13 @@
+package config
+
+func NewAliasesConfig() AliasesConfig {
+	return AliasesConfig{}
+}
+
+type AliasesConfig struct {
+	Regex bool `koanf:"regex" short:"r" description:"interpret nickname seeds as regular expressions instead of exact nicknames"`
+}
+
+func (cfg *AliasesConfig) Validate() error {
+	return nil
+}
@@ -0,0 +1,13 @@
+package config
+
+func NewIndexBuildConfig() IndexBuildConfig {
+	return IndexBuildConfig{}
+}
+
+type IndexBuildConfig struct {
+	Prune bool `koanf:"prune" description:"remove log files and archives from the index that were not found by this build, e.g. because they were deleted"`
+}
+
+func (cfg *IndexBuildConfig) Validate() error {
+	return nil
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+func NewJoinedConfig() JoinedConfig {
+	return JoinedConfig{}
+}
+
+type JoinedConfig struct {
+	Nickname       string         `koanf:"nickname" short:"n" description:"only list players whose nickname matches this regex"`
+	NicknameRegexp *regexp.Regexp `koanf:"-"`
+	Clan           string         `koanf:"clan" description:"only list players whose clan matches this regex"`
+	ClanRegexp     *regexp.Regexp `koanf:"-"`
+	Country        string         `koanf:"country" description:"comma separated list of numeric ISO 3166-1 country codes, e.g. 276,40"`
+	Countries      []int          `koanf:"-"`
+	MinVersion     int            `koanf:"min.version" description:"only list players with a client version greater than or equal to this version"`
+	MaxVersion     int            `koanf:"max.version" description:"only list players with a client version less than or equal to this version"`
+	IP             string         `koanf:"ip" description:"only list players with this IP address or from this CIDR range, e.g. 1.2.3.0/24"`
+	IPPrefix       netip.Prefix   `koanf:"-"`
+}
+
+func (cfg *JoinedConfig) Validate() error {
+	if cfg.Nickname != "" {
+		re, err := regexp.Compile(cfg.Nickname)
+		if err != nil {
+			return fmt.Errorf("invalid nickname regex: %w", err)
+		}
+		cfg.NicknameRegexp = re
+	}
+
+	if cfg.Clan != "" {
+		re, err := regexp.Compile(cfg.Clan)
+		if err != nil {
+			return fmt.Errorf("invalid clan regex: %w", err)
+		}
+		cfg.ClanRegexp = re
+	}
+
+	cfg.Countries = cfg.Countries[:0]
+	for _, code := range strings.Split(cfg.Country, ",") {
+		code = strings.TrimSpace(code)
+		if code == "" {
+			continue
+		}
+		country, err := strconv.Atoi(code)
+		if err != nil {
+			return fmt.Errorf("invalid country code %q: must be a numeric ISO 3166-1 code", code)
+		}
+		cfg.Countries = append(cfg.Countries, country)
+	}
+
+	if cfg.MinVersion < 0 || cfg.MaxVersion < 0 {
+		return errors.New("client versions must not be negative")
+	}
+	if cfg.MaxVersion > 0 && cfg.MinVersion > cfg.MaxVersion {
+		return errors.New("min version must be less than or equal to max version")
+	}
+
+	if cfg.IP != "" {
+		prefix, err := ParsePrefix(cfg.IP)
+		if err != nil {
+			return err
+		}
+		cfg.IPPrefix = prefix
+	}
+
+	return nil
+}
+
+// Matches reports whether a joined player passes all filters.
+func (cfg *JoinedConfig) Matches(ip string, version int, nickname, clan string, country int) bool {
+	if cfg.IPPrefix.IsValid() && !PrefixContains(cfg.IPPrefix, ip) {
+		return false
+	}
+	if cfg.NicknameRegexp != nil && !cfg.NicknameRegexp.MatchString(nickname) {
+		return false
+	}
+	if cfg.ClanRegexp != nil && !cfg.ClanRegexp.MatchString(clan) {
+		return false
+	}
+	if len(cfg.Countries) > 0 && !slices.Contains(cfg.Countries, country) {
+		return false
+	}
+	if cfg.MinVersion > 0 && version < cfg.MinVersion {
+		return false
+	}
+	if cfg.MaxVersion > 0 && version > cfg.MaxVersion {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+)
+
+func NewModerationConfig() ModerationConfig {
+	return ModerationConfig{}
+}
+
+type ModerationConfig struct {
+	Nickname       string         `koanf:"nickname" short:"n" description:"only list actions against players whose nickname matches this regex"`
+	NicknameRegexp *regexp.Regexp `koanf:"-"`
+	IP             string         `koanf:"ip" description:"only list actions against this IP address or CIDR range, e.g. 1.2.3.0/24"`
+	IPPrefix       netip.Prefix   `koanf:"-"`
+}
+
+func (cfg *ModerationConfig) Validate() error {
+	if cfg.Nickname != "" {
+		re, err := regexp.Compile(cfg.Nickname)
+		if err != nil {
+			return fmt.Errorf("invalid nickname regex: %w", err)
+		}
+		cfg.NicknameRegexp = re
+	}
+
+	if cfg.IP != "" {
+		prefix, err := ParsePrefix(cfg.IP)
+		if err != nil {
+			return err
+		}
+		cfg.IPPrefix = prefix
+	}
+
+	return nil
+}
+
+// Matches reports whether an action against a player with the given IP and nickname passes all filters.
+func (cfg *ModerationConfig) Matches(ip, nickname string) bool {
+	if cfg.IPPrefix.IsValid() && !PrefixContains(cfg.IPPrefix, ip) {
+		return false
+	}
+	if cfg.NicknameRegexp != nil && !cfg.NicknameRegexp.MatchString(nickname) {
+		return false
+	}
+	return true
+}
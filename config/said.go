@@ -2,18 +2,50 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jxsl13/twlog/match"
 )
 
 func NewSaidConfig() SaidConfig {
 	return SaidConfig{
 		Deduplicate: false,
+		Channel:     "all,team,whisper",
 	}
 }
 
 type SaidConfig struct {
-	Deduplicate bool `koanf:"deduplicate" short:"D" description:"deduplicate objects based on all fields"`
-	Extended    bool `koanf:"extended" short:"e" description:"add two additional fields, file and id to the output"`
-	IPsOnly     bool `koanf:"ips.only" short:"i" description:"only print IP addresses and depending on the command additional information"`
+	Deduplicate bool      `koanf:"deduplicate" short:"D" description:"deduplicate objects based on all fields"`
+	Extended    bool      `koanf:"extended" short:"e" description:"add three additional fields, file, time and id to the output"`
+	IPsOnly     bool      `koanf:"ips.only" short:"i" description:"only print IP addresses and depending on the command additional information"`
+	Since       string    `koanf:"since" description:"only include chat messages written at or after this time, e.g. '2024-01-02 15:04:05' or 'now'"`
+	SinceTime   time.Time `koanf:"-"`
+	Until       string    `koanf:"until" description:"only include chat messages written before this time, e.g. '2024-01-02 18:00'"`
+	UntilTime   time.Time `koanf:"-"`
+
+	IgnoreCase   bool     `koanf:"ignore.case" description:"match the search patterns case insensitively"`
+	FixedStrings bool     `koanf:"fixed.strings" description:"interpret the search patterns as literal strings instead of regular expressions, e.g. bot.xyz"`
+	PatternsFile string   `koanf:"patterns.file" description:"file that contains additional search patterns, one pattern per line"`
+	Patterns     []string `koanf:"-"` // patterns of the patterns file, the arguments are prepended before the search
+
+	Channel  string          `koanf:"channel" description:"comma separated list of chat channels to search, any of 'all', 'team', 'whisper' and 'server' (messages of the server itself, e.g. rcon say)"`
+	Channels []match.Channel `koanf:"-"`
+
+	Before  int `koanf:"before" short:"B" description:"print this number of log lines before each matching chat message, requires --extended"`
+	After   int `koanf:"after" description:"print this number of log lines after each matching chat message, requires --extended, unlike grep there is no -A, which is --include-archive"`
+	Context int `koanf:"context" short:"C" description:"print this number of log lines before and after each matching chat message, requires --extended"`
+}
+
+// MultiplePatterns returns true in case more than one distinct search pattern was passed,
+// in which case the output reports which pattern matched.
+func (cfg *SaidConfig) MultiplePatterns() bool {
+	return slices.ContainsFunc(cfg.Patterns, func(p string) bool {
+		return p != cfg.Patterns[0]
+	})
 }
 
 func (cfg *SaidConfig) Validate() error {
@@ -22,5 +54,128 @@ func (cfg *SaidConfig) Validate() error {
 		return errors.New("extended and ips only flags are mutually exclusive")
 	}
 
+	if cfg.Before < 0 || cfg.After < 0 || cfg.Context < 0 {
+		return errors.New("before, after and context must not be negative")
+	}
+
+	if cfg.Context > 0 {
+		if cfg.Before == 0 {
+			cfg.Before = cfg.Context
+		}
+		if cfg.After == 0 {
+			cfg.After = cfg.Context
+		}
+	}
+
+	if cfg.WithContext() && !cfg.Extended {
+		return errors.New("before, after and context require the extended flag")
+	}
+
+	if cfg.Since != "" {
+		t, err := ParseTime(cfg.Since)
+		if err != nil {
+			return fmt.Errorf("invalid since: %w", err)
+		}
+		cfg.SinceTime = t
+	}
+
+	if cfg.Until != "" {
+		t, err := ParseTime(cfg.Until)
+		if err != nil {
+			return fmt.Errorf("invalid until: %w", err)
+		}
+		cfg.UntilTime = t
+	}
+
+	if !cfg.SinceTime.IsZero() && !cfg.UntilTime.IsZero() && !cfg.SinceTime.Before(cfg.UntilTime) {
+		return errors.New("since must be before until")
+	}
+
+	channels, err := parseChannels(cfg.Channel)
+	if err != nil {
+		return err
+	}
+	cfg.Channels = channels
+
+	if cfg.PatternsFile != "" {
+		patterns, err := readPatterns(cfg.PatternsFile)
+		if err != nil {
+			return err
+		}
+		cfg.Patterns = patterns
+	}
+
 	return nil
 }
+
+// parseChannels parses a comma separated list of chat channels.
+func parseChannels(list string) ([]match.Channel, error) {
+	channels := make([]match.Channel, 0, len(match.Channels))
+	for _, elem := range strings.Split(list, ",") {
+		channel := match.Channel(strings.ToLower(strings.TrimSpace(elem)))
+		if channel == "" {
+			continue
+		}
+		if !slices.Contains(match.Channels, channel) {
+			return nil, fmt.Errorf("invalid channel %q: must be one of %v", channel, match.Channels)
+		}
+		if !slices.Contains(channels, channel) {
+			channels = append(channels, channel)
+		}
+	}
+
+	if len(channels) == 0 {
+		return nil, errors.New("channel must contain at least one chat channel")
+	}
+	return channels, nil
+}
+
+// readPatterns returns the non-empty lines of a patterns file.
+func readPatterns(filePath string) ([]string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read patterns file: %w", err)
+	}
+
+	patterns := make([]string, 0, 16)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("patterns file %s does not contain any patterns", filePath)
+	}
+	return patterns, nil
+}
+
+// InChannel reports whether messages of the given chat channel are searched.
+func (cfg *SaidConfig) InChannel(channel match.Channel) bool {
+	return slices.Contains(cfg.Channels, channel)
+}
+
+// WithContext reports whether the log lines around each matching chat message are requested.
+func (cfg *SaidConfig) WithContext() bool {
+	return cfg.Before > 0 || cfg.After > 0
+}
+
+// InTimeWindow reports whether t lies within the --since and --until bounds.
+// Lines without a timestamp are excluded as soon as any bound is set.
+func (cfg *SaidConfig) InTimeWindow(t time.Time) bool {
+	if cfg.SinceTime.IsZero() && cfg.UntilTime.IsZero() {
+		return true
+	}
+	if t.IsZero() {
+		return false
+	}
+	if !cfg.SinceTime.IsZero() && t.Before(cfg.SinceTime) {
+		return false
+	}
+	if !cfg.UntilTime.IsZero() && !t.Before(cfg.UntilTime) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+)
+
+func NewSessionsConfig() SessionsConfig {
+	return SessionsConfig{}
+}
+
+type SessionsConfig struct {
+	Nickname       string         `koanf:"nickname" short:"n" description:"only list sessions in which one of the player's nicknames matches this regex"`
+	NicknameRegexp *regexp.Regexp `koanf:"-"`
+	IP             string         `koanf:"ip" description:"only list sessions of this IP address or CIDR range, e.g. 1.2.3.0/24"`
+	IPPrefix       netip.Prefix   `koanf:"-"`
+}
+
+func (cfg *SessionsConfig) Validate() error {
+	if cfg.Nickname != "" {
+		re, err := regexp.Compile(cfg.Nickname)
+		if err != nil {
+			return fmt.Errorf("invalid nickname regex: %w", err)
+		}
+		cfg.NicknameRegexp = re
+	}
+
+	if cfg.IP != "" {
+		prefix, err := ParsePrefix(cfg.IP)
+		if err != nil {
+			return err
+		}
+		cfg.IPPrefix = prefix
+	}
+
+	return nil
+}
+
+// Matches reports whether a session of a player with the given IP and nicknames passes all filters.
+func (cfg *SessionsConfig) Matches(ip string, nicknames []string) bool {
+	if cfg.IPPrefix.IsValid() && !PrefixContains(cfg.IPPrefix, ip) {
+		return false
+	}
+
+	if cfg.NicknameRegexp == nil {
+		return true
+	}
+	for _, nickname := range nicknames {
+		if cfg.NicknameRegexp.MatchString(nickname) {
+			return true
+		}
+	}
+	return false
+}
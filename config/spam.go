@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jxsl13/twlog/internal/spam"
+)
+
+func NewSpamConfig() SpamConfig {
+	return SpamConfig{
+		Similarity:       spam.SimilarityMasked,
+		JaccardThreshold: 0.6,
+		NGramSize:        3,
+		MinIPs:           3,
+		Window:           5 * time.Minute,
+	}
+}
+
+type SpamConfig struct {
+	Similarity       string        `koanf:"similarity" description:"how chat messages are clustered, one of 'exact' (case and whitespace insensitive), 'masked' (additionally ignores numbers and URLs) or 'jaccard' (similar character n-grams of the masked messages)"`
+	JaccardThreshold float64       `koanf:"jaccard.threshold" description:"minimum jaccard similarity between 0 and 1 of messages of the same cluster"`
+	NGramSize        int           `koanf:"ngram.size" description:"length of the character n-grams that are compared by the jaccard similarity"`
+	MinIPs           int           `koanf:"min.ips" description:"minimum number of distinct IPs that must send a message of a cluster within the window"`
+	Window           time.Duration `koanf:"window" description:"time window in which the messages of a spam wave must be sent"`
+	IPsOnly          bool          `koanf:"ips.only" short:"i" description:"only print the IP addresses and phrases of the spam waves, e.g. in order to create a ban list"`
+}
+
+func (cfg *SpamConfig) Validate() error {
+	allowed := []string{spam.SimilarityExact, spam.SimilarityMasked, spam.SimilarityJaccard}
+	cfg.Similarity = strings.ToLower(cfg.Similarity)
+	if !slices.Contains(allowed, cfg.Similarity) {
+		return fmt.Errorf("invalid similarity %q: must be one of %v", cfg.Similarity, allowed)
+	}
+
+	if cfg.JaccardThreshold <= 0 || cfg.JaccardThreshold > 1 {
+		return fmt.Errorf("invalid jaccard threshold %v: must be greater than 0 and at most 1", cfg.JaccardThreshold)
+	}
+	if cfg.NGramSize < 1 {
+		return fmt.Errorf("invalid n-gram size %d: must be at least 1", cfg.NGramSize)
+	}
+	if cfg.MinIPs < 1 {
+		return fmt.Errorf("invalid min IPs %d: must be at least 1", cfg.MinIPs)
+	}
+	if cfg.Window < 0 {
+		return fmt.Errorf("invalid window %s: must not be negative", cfg.Window)
+	}
+	return nil
+}
+
+// ToSpamConfig returns the settings of the spam detection.
+func (cfg *SpamConfig) ToSpamConfig() spam.Config {
+	return spam.Config{
+		Similarity: cfg.Similarity,
+		Threshold:  cfg.JaccardThreshold,
+		NGramSize:  cfg.NGramSize,
+		MinIPs:     cfg.MinIPs,
+		Window:     cfg.Window,
+	}
+}
@@ -0,0 +1,22 @@
+package config
+
+import (
+	"fmt"
+)
+
+func NewStatsConfig() StatsConfig {
+	return StatsConfig{
+		Top: 10,
+	}
+}
+
+type StatsConfig struct {
+	Top int `koanf:"top" short:"N" description:"number of entries that are listed per statistic, 0 lists all entries"`
+}
+
+func (cfg *StatsConfig) Validate() error {
+	if cfg.Top < 0 {
+		return fmt.Errorf("invalid top %d: must not be negative", cfg.Top)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jxsl13/twlog/match"
+)
+
+var timeLayouts = []string{
+	match.TimestampLayout,
+	"2006-01-02 15:04",
+	"2006-01-02",
+	time.RFC3339,
+}
+
+// ParseTime parses a user provided point in time or the keyword now.
+// Times without a time zone are interpreted as local time, just like log timestamps.
+func ParseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if strings.EqualFold(s, "now") {
+		return time.Now(), nil
+	}
+	for _, layout := range timeLayouts {
+		t, err := time.ParseInLocation(layout, s, time.Local)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: expected one of the formats %q", s, timeLayouts)
+}
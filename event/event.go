@@ -0,0 +1,134 @@
+package event
+
+import (
+	"time"
+
+	"github.com/jxsl13/twlog/match"
+)
+
+// Event is a single log line that could be parsed into a typed event.
+type Event interface {
+	Metadata() Meta
+}
+
+// Meta is shared by all events and describes where and when the event was logged.
+type Meta struct {
+	LineNumber int       `json:"line_number"`
+	Time       time.Time `json:"time"`
+	Line       string    `json:"line"`
+}
+
+func (m Meta) Metadata() Meta {
+	return m
+}
+
+// JoinEvent is logged when a player joins the server.
+// Depending on the log dialect Port and Version are 0,
+// Nickname and Clan are empty and Country is -1.
+type JoinEvent struct {
+	Meta
+	ID       int
+	IP       string
+	Port     int
+	Version  int
+	Nickname string
+	Clan     string
+	Country  int
+}
+
+// EnterEvent is the chat announcement of a player that entered the game.
+// The line does not contain the client id, the Scanner resolves it from the preceding join line
+// and sets it to -1 in case it is unknown.
+type EnterEvent struct {
+	Meta
+	ID       int
+	Nickname string
+}
+
+// TeamJoinEvent is logged when a player joins a team or the spectators.
+type TeamJoinEvent struct {
+	Meta
+	ID       int
+	Nickname string
+	Team     int
+}
+
+// ClientVersionEvent is logged by DDNet servers when the client version of a player is known.
+type ClientVersionEvent struct {
+	Meta
+	ID      int
+	Version int
+}
+
+type LeaveEvent struct {
+	Meta
+	ID     int
+	IP     string
+	Reason string
+}
+
+// Kicked reports whether the player was kicked from the server.
+func (e LeaveEvent) Kicked() bool {
+	return match.KickReason(e.Reason)
+}
+
+// ChatEvent is a message in the chat.
+// Target is the client id of the receiver of a whisper and -1 otherwise.
+type ChatEvent struct {
+	Meta
+	ID       int
+	Nickname string
+	Text     string
+	Channel  match.Channel
+	Team     int
+	Target   int
+}
+
+// FromServer reports whether the message was sent by the server instead of a player.
+func (e ChatEvent) FromServer() bool {
+	return e.Channel == match.ChannelServer
+}
+
+// NameChangeEvent is the chat announcement of a nickname change.
+// The line does not contain the client id, the Scanner resolves it from the client that is known by the old name
+// and sets it to -1 in case it is unknown or several clients share the old name.
+type NameChangeEvent struct {
+	Meta
+	ID      int
+	OldName string
+	NewName string
+}
+
+type MapChangeEvent struct {
+	Meta
+	Map string
+}
+
+// BanEvent is logged when an IP address or an IP range is banned.
+// Target contains the normalized IP address or the IP range.
+type BanEvent struct {
+	Meta
+	Target    string
+	Duration  time.Duration
+	Permanent bool
+	Reason    string
+}
+
+type UnbanEvent struct {
+	Meta
+	Target string
+}
+
+// RconEvent is logged when an authenticated player executes a remote console command.
+type RconEvent struct {
+	Meta
+	ID      int
+	Command string
+}
+
+type VoteKickEvent struct {
+	Meta
+	Caller string
+	Target string
+	Reason string
+}
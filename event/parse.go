@@ -0,0 +1,64 @@
+package event
+
+import "github.com/jxsl13/twlog/match"
+
+// Parse turns a single log line into an event.
+// This is the one place that needs to be extended in order to support additional log dialects.
+func Parse(lineNumber int, line string) (Event, bool) {
+	return ParseWithNames(lineNumber, line, nil)
+}
+
+// ParseWithNames is like Parse, but uses the already known nicknames of the clients
+// in order to split chat lines whose nickname or message contains ": ".
+func ParseWithNames(lineNumber int, line string, names match.Names) (Event, bool) {
+	meta := Meta{
+		LineNumber: lineNumber,
+		Line:       line,
+	}
+	meta.Time, _ = match.Timestamp(line)
+
+	if d, ok := match.JoinDetailed(line); ok {
+		return JoinEvent{
+			Meta:     meta,
+			ID:       d.ID,
+			IP:       d.IP,
+			Port:     d.Port,
+			Version:  d.Version,
+			Nickname: d.Name,
+			Clan:     d.Clan,
+			Country:  d.Country,
+		}, true
+	} else if id, ip, reason, ok := match.Leave(line); ok {
+		return LeaveEvent{Meta: meta, ID: id, IP: ip, Reason: reason}, true
+	} else if target, d, permanent, reason, ok := match.Ban(line); ok {
+		return BanEvent{Meta: meta, Target: target, Duration: d, Permanent: permanent, Reason: reason}, true
+	} else if target, ok := match.Unban(line); ok {
+		return UnbanEvent{Meta: meta, Target: target}, true
+	} else if id, command, ok := match.Rcon(line); ok {
+		return RconEvent{Meta: meta, ID: id, Command: command}, true
+	} else if caller, target, reason, ok := match.VoteKick(line); ok {
+		return VoteKickEvent{Meta: meta, Caller: caller, Target: target, Reason: reason}, true
+	} else if id, nickname, team, ok := match.TeamJoin(line); ok {
+		return TeamJoinEvent{Meta: meta, ID: id, Nickname: nickname, Team: team}, true
+	} else if id, version, ok := match.ClientVersion(line); ok {
+		return ClientVersionEvent{Meta: meta, ID: id, Version: version}, true
+	} else if nickname, ok := match.Enter(line); ok {
+		return EnterEvent{Meta: meta, ID: -1, Nickname: nickname}, true
+	} else if oldName, newName, ok := match.NameChange(line); ok {
+		return NameChangeEvent{Meta: meta, ID: -1, OldName: oldName, NewName: newName}, true
+	} else if d, ok := match.ChatDetailedWithNames(line, names); ok {
+		return ChatEvent{
+			Meta:     meta,
+			ID:       d.ID,
+			Nickname: d.Name,
+			Text:     d.Text,
+			Channel:  d.Channel,
+			Team:     d.Team,
+			Target:   d.Target,
+		}, true
+	} else if mapName, ok := match.MapChange(line); ok {
+		return MapChangeEvent{Meta: meta, Map: mapName}, true
+	}
+
+	return nil, false
+}
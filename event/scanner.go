@@ -0,0 +1,304 @@
+package event
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/jxsl13/twlog/match"
+)
+
+// Scanner reads log lines from a reader and provides them as a stream of events.
+// Lines that cannot be parsed are skipped.
+//
+//	s := event.NewScanner(r)
+//	for s.Scan() {
+//		switch e := s.Event().(type) {
+//		case event.ChatEvent:
+//			...
+//		}
+//	}
+//	if err := s.Err(); err != nil {
+//		...
+//	}
+type Scanner struct {
+	scanner    *bufio.Scanner
+	lineNumber int
+	event      Event
+	malformed  int
+
+	keepRawLines bool
+	rawLines     []RawLine
+
+	// client id -> nickname
+	names map[int]string
+	// client ids of joins without nickname, the most recent one is last
+	unnamed []int
+
+	parser Parser
+	// detect chooses the parser based on the first detectLines lines
+	detect      func(lines []string) Parser
+	detectLines int
+	// lines that were read ahead during the detection
+	lookahead []string
+
+	// replayer provides the events instead of parsing lines
+	replayer Replayer
+	err      error
+}
+
+// Replayer is implemented by readers that provide already parsed events, e.g. the log files of an index.
+// Scanners replay their events instead of parsing the lines that they read.
+type Replayer interface {
+	io.Reader
+	// Replay returns the next event and false once there are no more events.
+	Replay() (Event, bool, error)
+}
+
+// Parser turns a single log line into an event, see ParseWithNames.
+type Parser interface {
+	Parse(lineNumber int, line string, names match.Names) (Event, bool)
+}
+
+// ParserFunc is a function that implements the Parser interface.
+type ParserFunc func(lineNumber int, line string, names match.Names) (Event, bool)
+
+func (f ParserFunc) Parse(lineNumber int, line string, names match.Names) (Event, bool) {
+	return f(lineNumber, line, names)
+}
+
+// RawLine is a line of the input, no matter whether it could be parsed.
+type RawLine struct {
+	LineNumber int
+	Line       string
+}
+
+func NewScanner(r io.Reader) *Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Split(bufio.ScanLines)
+	replayer, _ := r.(Replayer)
+	return &Scanner{
+		scanner:  scanner,
+		names:    make(map[int]string, 64),
+		parser:   ParserFunc(ParseWithNames),
+		replayer: replayer,
+	}
+}
+
+// SetParser replaces the built-in parser, e.g. with the parser of a log dialect.
+// It must be called before the first call to Scan.
+func (s *Scanner) SetParser(p Parser) {
+	s.parser = p
+}
+
+// DetectParser makes the scanner choose its parser based on the first n lines of the input.
+// These lines are read ahead by the first call to Scan, which blocks until n lines
+// have been read or the input ends.
+func (s *Scanner) DetectParser(n int, detect func(lines []string) Parser) {
+	s.detect = detect
+	s.detectLines = n
+}
+
+// nextLine returns the lines that were read ahead before reading further lines.
+func (s *Scanner) nextLine() (string, bool) {
+	if len(s.lookahead) > 0 {
+		line := s.lookahead[0]
+		s.lookahead = s.lookahead[1:]
+		return line, true
+	}
+	if !s.scanner.Scan() {
+		return "", false
+	}
+	return s.scanner.Text(), true
+}
+
+// detectParser reads the first lines ahead and chooses the parser.
+func (s *Scanner) detectParser() {
+	detect := s.detect
+	s.detect = nil
+
+	lines := make([]string, 0, s.detectLines)
+	for len(lines) < s.detectLines && s.scanner.Scan() {
+		lines = append(lines, s.scanner.Text())
+	}
+	s.lookahead = lines
+
+	if p := detect(lines); p != nil {
+		s.parser = p
+	}
+}
+
+// Scan advances the scanner to the next event.
+// It returns false when there are no more events, either because the end of the input
+// was reached or because of an error.
+func (s *Scanner) Scan() bool {
+	s.rawLines = s.rawLines[:0]
+	if s.replayer != nil {
+		return s.scanReplay()
+	}
+	if s.detect != nil {
+		s.detectParser()
+	}
+
+	for {
+		line, ok := s.nextLine()
+		if !ok {
+			break
+		}
+		s.lineNumber++
+
+		if s.keepRawLines {
+			s.rawLines = append(s.rawLines, RawLine{LineNumber: s.lineNumber, Line: line})
+		}
+		e, ok := s.parser.Parse(s.lineNumber, line, s.name)
+		if !ok {
+			if _, hasTime := match.Timestamp(line); !hasTime && strings.TrimSpace(line) != "" {
+				s.malformed++
+			}
+			continue
+		}
+		s.event = s.observe(e)
+		return true
+	}
+	s.event = nil
+	return false
+}
+
+// scanReplay advances to the next replayed event, whose line is the only raw line.
+// The client ids of replayed events have already been resolved.
+func (s *Scanner) scanReplay() bool {
+	e, ok, err := s.replayer.Replay()
+	if err != nil || !ok {
+		s.err = err
+		s.event = nil
+		return false
+	}
+
+	meta := e.Metadata()
+	s.lineNumber = meta.LineNumber
+	if s.keepRawLines {
+		s.rawLines = append(s.rawLines, RawLine{LineNumber: meta.LineNumber, Line: meta.Line})
+	}
+	s.event = e
+	return true
+}
+
+func (s *Scanner) name(id int) (string, bool) {
+	name, ok := s.names[id]
+	return name, ok
+}
+
+// observe keeps track of the nicknames of the connected clients and resolves the client ids
+// of the events whose lines do not contain them.
+func (s *Scanner) observe(e Event) Event {
+	switch e := e.(type) {
+	case JoinEvent:
+		s.named(e.ID)
+		if e.Nickname != "" {
+			s.names[e.ID] = e.Nickname
+		} else {
+			delete(s.names, e.ID)
+			// DDNet announces the nickname after the join line
+			s.unnamed = append(s.unnamed, e.ID)
+		}
+	case EnterEvent:
+		if e.ID < 0 {
+			e.ID = s.enteredID(e.Nickname)
+		}
+		if e.ID >= 0 {
+			s.named(e.ID)
+			s.names[e.ID] = e.Nickname
+		}
+		return e
+	case TeamJoinEvent:
+		s.named(e.ID)
+		s.names[e.ID] = e.Nickname
+	case ChatEvent:
+		if !e.FromServer() {
+			s.named(e.ID)
+			s.names[e.ID] = e.Nickname
+		}
+	case NameChangeEvent:
+		if e.ID < 0 {
+			e.ID = s.idOf(e.OldName)
+		}
+		if e.ID >= 0 {
+			s.names[e.ID] = e.NewName
+		}
+		return e
+	case LeaveEvent:
+		s.named(e.ID)
+		delete(s.names, e.ID)
+	}
+	return e
+}
+
+// enteredID returns the client that is already known by the nickname or the most recent join without nickname.
+func (s *Scanner) enteredID(nickname string) int {
+	if id := s.idOf(nickname); id >= 0 {
+		return id
+	}
+	if len(s.unnamed) == 0 {
+		return -1
+	}
+	return s.unnamed[len(s.unnamed)-1]
+}
+
+// idOf returns the only client that is known by the nickname and -1 in case there is none or several.
+func (s *Scanner) idOf(nickname string) int {
+	found := -1
+	for id, name := range s.names {
+		if name != nickname {
+			continue
+		}
+		if found >= 0 {
+			return -1
+		}
+		found = id
+	}
+	return found
+}
+
+// named removes a client from the joins without nickname.
+func (s *Scanner) named(id int) {
+	s.unnamed = slices.DeleteFunc(s.unnamed, func(unnamed int) bool {
+		return unnamed == id
+	})
+}
+
+// Event returns the most recent event generated by a call to Scan.
+func (s *Scanner) Event() Event {
+	return s.event
+}
+
+// KeepRawLines makes the scanner keep all lines that are read by Scan.
+func (s *Scanner) KeepRawLines() {
+	s.keepRawLines = true
+}
+
+// RawLines returns the lines that were read by the most recent call to Scan in case KeepRawLines was called.
+// The last line is the line of the current event, unless Scan returned false.
+// The returned slice is only valid until the next call to Scan.
+func (s *Scanner) RawLines() []RawLine {
+	return s.rawLines
+}
+
+// Malformed returns the number of non-empty lines that were skipped, because they
+// neither contain a known event nor start with a log timestamp.
+func (s *Scanner) Malformed() int {
+	return s.malformed
+}
+
+// Err returns the first non-EOF error that was encountered by the Scanner.
+func (s *Scanner) Err() error {
+	if s.err != nil {
+		return s.err
+	}
+	err := s.scanner.Err()
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package event
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestScanner(t *testing.T) {
+	log := strings.Join([]string{
+		"2024-05-01 18:00:00 I server: maps/Kobra 4.map sha256 is 0e4d5b2b",
+		"2024-05-01 18:00:03 I server: player has entered the game. ClientID=0 addr=<{1.2.3.4:53212}> sevendown=0",
+		"2024-05-01 18:00:04 I chat: *** 'nameless tee' changed name to 'OPlayer'",
+		"some line that is not an event",
+		"2024-05-01 18:00:10 I chat: 0:-2:OPlayer: hi all",
+		"[66326cf0][server]: client dropped. id=0 addr=1.2.3.4:53212 reason='Timeout'",
+	}, "\n")
+
+	s := NewScanner(strings.NewReader(log))
+	events := make([]Event, 0, 5)
+	for s.Scan() {
+		events = append(events, s.Event())
+	}
+	if err := s.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(events) != 5 {
+		t.Fatalf("expected 5 events, got %d: %#v", len(events), events)
+	}
+
+	if e, ok := events[0].(MapChangeEvent); !ok || e.Map != "Kobra 4" {
+		t.Errorf("expected map change event for Kobra 4, got %#v", events[0])
+	}
+	if e, ok := events[1].(JoinEvent); !ok || e.ID != 0 || e.IP != "1.2.3.4" {
+		t.Errorf("expected join event, got %#v", events[1])
+	}
+	if e, ok := events[2].(NameChangeEvent); !ok || e.OldName != "nameless tee" || e.NewName != "OPlayer" {
+		t.Errorf("expected name change event, got %#v", events[2])
+	}
+	if e, ok := events[3].(ChatEvent); !ok || e.Nickname != "OPlayer" || e.Text != "hi all" || e.LineNumber != 5 {
+		t.Errorf("expected chat event on line 5, got %#v", events[3])
+	}
+	if e, ok := events[4].(LeaveEvent); !ok || e.ID != 0 || e.IP != "1.2.3.4" || e.Reason != "Timeout" || e.Time.IsZero() {
+		t.Errorf("expected timestamped leave event, got %#v", events[4])
+	}
+}
+
+func TestScannerKnownNames(t *testing.T) {
+	log := strings.Join([]string{
+		"2024-05-01 18:00:03 I server: player has entered the game. ClientID=0 addr=<{1.2.3.4:53212}> sevendown=0",
+		"2024-05-01 18:00:03 I game: team_join player='0:a: b' team=0",
+		"2024-05-01 18:00:10 I chat: 0:-2:a: b: hello: world",
+		"2024-05-01 18:00:11 I chat: *** 'a: b' changed name to 'c: d'",
+		"2024-05-01 18:00:12 I chat: 0:-2:c: d: bye",
+		"[66326cf0][server]: client dropped. id=0 addr=1.2.3.4:53212 reason=''",
+		// without any known name the first split wins
+		"2024-05-01 18:00:13 I chat: 0:-2:c: d: bye",
+	}, "\n")
+
+	s := NewScanner(strings.NewReader(log))
+	chats := make([]string, 0, 3)
+	for s.Scan() {
+		if e, ok := s.Event().(ChatEvent); ok {
+			chats = append(chats, e.Nickname+"|"+e.Text)
+		}
+	}
+
+	expected := "a: b|hello: world,c: d|bye,c|d: bye"
+	if actual := strings.Join(chats, ","); actual != expected {
+		t.Fatalf("expected %q, got %q", expected, actual)
+	}
+}
+
+func TestScannerResolvesIDs(t *testing.T) {
+	log := strings.Join([]string{
+		"2024-05-01 18:00:03 I server: player has entered the game. ClientID=0 addr=<{1.2.3.4:53212}> sevendown=0",
+		"2024-05-01 18:00:04 I chat: *** 'twin' entered and joined the game",
+		"2024-05-01 18:00:05 I server: player has entered the game. ClientID=1 addr=<{5.6.7.8:41234}> sevendown=0",
+		"2024-05-01 18:00:06 I chat: *** 'other' entered and joined the game",
+		"2024-05-01 18:00:07 I chat: *** 'other' changed name to 'renamed'",
+		// both clients are known as twin, which is why the name change cannot be resolved
+		"2024-05-01 18:00:08 I chat: 1:-2:twin: me too",
+		"2024-05-01 18:00:09 I chat: *** 'twin' changed name to 'single'",
+		"2024-05-01 18:00:10 I chat: *** 'nobody' entered and joined the game",
+	}, "\n")
+
+	s := NewScanner(strings.NewReader(log))
+	ids := make([]string, 0, 5)
+	for s.Scan() {
+		switch e := s.Event().(type) {
+		case EnterEvent:
+			ids = append(ids, fmt.Sprintf("%s=%d", e.Nickname, e.ID))
+		case NameChangeEvent:
+			ids = append(ids, fmt.Sprintf("%s>%s=%d", e.OldName, e.NewName, e.ID))
+		}
+	}
+
+	expected := "twin=0,other=1,other>renamed=1,twin>single=-1,nobody=-1"
+	if actual := strings.Join(ids, ","); actual != expected {
+		t.Fatalf("expected %q, got %q", expected, actual)
+	}
+}
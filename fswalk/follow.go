@@ -0,0 +1,270 @@
+package fswalk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jxsl13/twlog/archive"
+)
+
+// DefaultPollInterval is used by Follow in case no poll interval is configured.
+const DefaultPollInterval = time.Second
+
+// Follow walks all files like Walk, but keeps log files open like tail -F.
+// The reader that is passed to do blocks at the end of a log file until new data is appended.
+// Truncated files are read again from the start and rotated files are reopened, which is why do
+// should keep its state, e.g. which client id belongs to which IP, across reads.
+// Log files that are created in the search dirs later on are followed as well.
+// Archives are searched once and stdin is read until its end.
+//
+// Every file is followed concurrently, the concurrency limit and the Done callback are not used.
+// Follow returns once ctx is canceled or do returns an error.
+func Follow(ctx context.Context, cfg WalkConfig, do func(filePath string, file io.Reader) error) error {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = DefaultPollInterval
+	}
+
+	ctx, cancelCause := context.WithCancelCause(ctx)
+	defer cancelCause(errors.New("follow default canceled"))
+	do = scanned(ctx, do)
+
+	var (
+		wg       = &sync.WaitGroup{}
+		followed = make(map[string]bool, 16)
+		opened   = &openedFiles{}
+		initial  = true
+	)
+
+	start := func(j job) {
+		followed[j.path] = true
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var err error
+			switch j.kind {
+			case jobFile:
+				err = followFile(ctx, cfg.PollInterval, opened, j.path, do)
+			case jobArchive:
+				_, err = walkArchive(ctx, cfg, nil, j.path, func(walkFunc archive.WalkFunc) error {
+					return archive.Walk(j.path, walkFunc)
+				}, do)
+			case jobStdin:
+				_, err = walkStdin(ctx, cfg, nil, do)
+			}
+			if err != nil && ctx.Err() == nil {
+				cancelCause(fmt.Errorf("error while following %s: %w", j.path, err))
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		jobs, err := collectJobs(ctx, cfg)
+		if err != nil && ctx.Err() == nil {
+			cancelCause(err)
+		}
+
+		for _, j := range jobs {
+			if followed[j.path] {
+				continue
+			}
+
+			if !initial {
+				// archives that appear later on are usually rotated log files that have already been followed
+				if j.kind == jobArchive {
+					followed[j.path] = true
+					continue
+				}
+
+				// renamed files are still followed by the reader that opened them
+				if opened.Contains(j.path) {
+					followed[j.path] = true
+					continue
+				}
+			}
+			start(j)
+		}
+		initial = false
+
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+
+			err := context.Cause(ctx)
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				// stopped by the user
+				return nil
+			}
+			return err
+		case <-ticker.C:
+		}
+	}
+}
+
+// openedFiles keeps track of all files that have been opened by any follow reader.
+type openedFiles struct {
+	mu    sync.Mutex
+	files []os.FileInfo
+}
+
+func (o *openedFiles) Add(fi os.FileInfo) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.files = append(o.files, fi)
+}
+
+// Contains returns true in case the file at path has already been opened, e.g. under a different name.
+func (o *openedFiles) Contains(path string) bool {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, opened := range o.files {
+		if os.SameFile(fi, opened) {
+			return true
+		}
+	}
+	return false
+}
+
+func followFile(
+	ctx context.Context,
+	interval time.Duration,
+	opened *openedFiles,
+	filePath string,
+	do func(filePath string, file io.Reader) error,
+) error {
+	r := &followReader{
+		ctx:      ctx,
+		path:     filePath,
+		interval: interval,
+		opened:   opened,
+	}
+
+	err := r.open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	return do(filePath, r)
+}
+
+// followReader reads a file like tail -F.
+// It blocks at the end of the file until new data is appended, the file is truncated or rotated,
+// or ctx is done.
+type followReader struct {
+	ctx      context.Context
+	path     string
+	interval time.Duration
+	opened   *openedFiles
+
+	f      *os.File
+	offset int64
+	// the file at path has been replaced, the old file is read until its end before the new one is opened
+	rotated bool
+}
+
+func (r *followReader) open() error {
+	f, err := os.Open(r.path)
+	if err != nil {
+		return fmt.Errorf("failed to open file %s: %w", r.path, err)
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat file %s: %w", r.path, err)
+	}
+	r.opened.Add(fi)
+
+	if r.f != nil {
+		r.f.Close()
+	}
+	r.f = f
+	r.offset = 0
+	r.rotated = false
+	return nil
+}
+
+func (r *followReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.f.Read(p)
+		r.offset += int64(n)
+		if n > 0 {
+			return n, nil
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, err
+		}
+
+		if r.rotated {
+			// data that was appended before the rotation has been read, continue with the new file
+			err = r.open()
+			if err == nil {
+				continue
+			}
+			if !errors.Is(err, os.ErrNotExist) {
+				return 0, err
+			}
+		}
+
+		// end of file, wait for new data
+		select {
+		case <-r.ctx.Done():
+			return 0, r.ctx.Err()
+		case <-time.After(r.interval):
+		}
+
+		err = r.check()
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// check marks rotated files, which are reopened once the old file has been drained, and rewinds truncated files.
+func (r *followReader) check() error {
+	fi, err := os.Stat(r.path)
+	if err != nil {
+		// rotated, but the new file has not been created yet
+		return nil
+	}
+
+	current, err := r.f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat file %s: %w", r.path, err)
+	}
+
+	if !os.SameFile(fi, current) {
+		// data may have been appended to the old file after it was read until its end,
+		// which is why it is drained before the new file is opened
+		r.rotated = true
+		return nil
+	}
+
+	if fi.Size() < r.offset {
+		_, err = r.f.Seek(0, io.SeekStart)
+		if err != nil {
+			return fmt.Errorf("failed to rewind truncated file %s: %w", r.path, err)
+		}
+		r.offset = 0
+	}
+	return nil
+}
+
+func (r *followReader) Close() error {
+	return r.f.Close()
+}
@@ -0,0 +1,71 @@
+package fswalk
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFollowReaderRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "server.log")
+	err := os.WriteFile(path, []byte("a\n"), 0o644)
+	if err != nil {
+		t.Fatalf("failed to write log file: %v", err)
+	}
+
+	r := &followReader{
+		ctx:      context.Background(),
+		path:     path,
+		interval: time.Millisecond,
+		opened:   &openedFiles{},
+	}
+	err = r.open()
+	if err != nil {
+		t.Fatalf("failed to open log file: %v", err)
+	}
+	defer r.Close()
+
+	read := func(expected string) {
+		t.Helper()
+		p := make([]byte, len(expected))
+		_, err := io.ReadFull(r, p)
+		if err != nil {
+			t.Fatalf("failed to read: %v", err)
+		}
+		if string(p) != expected {
+			t.Fatalf("expected %q, got %q", expected, p)
+		}
+	}
+	read("a\n")
+
+	// the server writes a last line before the log file is rotated
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("failed to open log file: %v", err)
+	}
+	_, err = f.WriteString("b\n")
+	f.Close()
+	if err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	err = os.Rename(path, path+".1")
+	if err != nil {
+		t.Fatalf("failed to rotate: %v", err)
+	}
+	err = os.WriteFile(path, []byte("c\n"), 0o644)
+	if err != nil {
+		t.Fatalf("failed to write new log file: %v", err)
+	}
+
+	// the rotation is detected while the reader waits at the end of the old file
+	err = r.check()
+	if err != nil {
+		t.Fatalf("failed to check log file: %v", err)
+	}
+	read("b\n")
+	read("c\n")
+}
@@ -6,79 +6,108 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"regexp"
 	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/jxsl13/twlog/archive"
 	"github.com/jxsl13/twlog/ctxutils"
+	"github.com/jxsl13/twlog/internal/diag"
 )
 
+// Stdin is the input path that reads from standard input.
+const Stdin = "-"
+
 type WalkConfig struct {
-	SearchDir       string
+	SearchDirs      []string
 	IncludeArchives bool
 	ArchiveRegexp   *regexp.Regexp
 	FileRegexp      *regexp.Regexp
 	Concurrency     int
-}
 
-func Walk(ctx context.Context, cfg WalkConfig, do func(filePath string, file io.Reader) error) error {
-	cfg.Concurrency = max(1, cfg.Concurrency)
+	// ExcludeRegexp is optional and skips matching directories, files and archive entries.
+	// It is matched against the slash separated path relative to the search dir or archive
+	// with a leading slash, e.g. /backup/server.log, so the location of the search dir itself is never excluded.
+	ExcludeRegexp *regexp.Regexp
+	// MaxDepth limits the depth of the searched directories, 1 only searches the files directly in a search dir.
+	// 0 is unlimited.
+	MaxDepth int
+	// FollowSymlinks searches files and directories that symbolic links point to.
+	FollowSymlinks bool
+	// GroupRegexp is optional and groups the log files and archives of one server by the first capture group
+	// or the whole match in case there is no capture group. The files of a group are processed sequentially
+	// in chronological order instead of concurrently. Files that do not match are not grouped.
+	// The entries of an archive that does not match are matched by their path, e.g. /logs.tar.gz@srv1.log,
+	// and only grouped with the entries of the same archive. The callback receives the group, see GroupOf.
+	GroupRegexp *regexp.Regexp
+	// PollInterval is the interval in which Follow checks for new data, rotated files and new files.
+	PollInterval time.Duration
 
-	ctx, cancelCause := context.WithCancelCause(ctx)
-	defer cancelCause(errors.New("walk default canceled"))
+	// Inputs are explicitly passed log files, archives or directories that are searched before the search dirs.
+	// Explicit files are searched no matter whether they match the file regex.
+	// Stdin reads from the Stdin reader, archives are detected automatically.
+	Inputs []string
+	Stdin  io.Reader
 
-	files := make([]string, 0, 16)
-	archives := make([]string, 0, 1)
+	// Done is optional and called once a log file or archive has been processed.
+	// It is called with all file paths that were passed to the walk function for that log file or archive.
+	// Calls happen in walk order, meaning that Done is only called after all previous log files and archives
+	// have been processed, even if they are processed concurrently.
+	Done func(filePaths ...string) error
 
-	entryDir, err := filepath.Abs(cfg.SearchDir)
-	if err != nil {
-		return fmt.Errorf("failed to get absolute path of root dir or file: %w", err)
-	}
+	// Cache is optional and allows to skip log files and archives that did not change since they were read.
+	Cache Cache
+}
 
-	// collect log file and archive paths
-	err = filepath.WalkDir(entryDir, func(path string, info os.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
+// Cache provides the content of log files and archives that did not change since they were read, e.g. from an index.
+type Cache interface {
+	// Cached is called before a log file or archive at path is read. In case it returns true,
+	// the file is not read and the cache is responsible for calling do for its log files.
+	// filePaths are the paths of the log files, which are passed to the Done function.
+	Cached(path string, info fs.FileInfo, do func(filePath string, file io.Reader) error) (filePaths []string, cached bool, err error)
+	// Walked is called after a log file or archive at path has been read.
+	Walked(path string, info fs.FileInfo, filePaths []string) error
+}
 
-		err = ctxutils.Done(ctx)
-		if err != nil {
-			return err
-		}
+type jobKind int
 
-		// skip non-files
-		if !info.Type().IsRegular() {
-			return nil
-		}
+const (
+	jobFile jobKind = iota
+	jobArchive
+	jobStdin
+)
 
-		if cfg.IncludeArchives && cfg.ArchiveRegexp != nil && cfg.ArchiveRegexp.MatchString(path) {
-			archives = append(archives, path)
-			return nil
-		}
+type job struct {
+	kind jobKind
+	path string
+}
 
-		if cfg.FileRegexp == nil || !cfg.FileRegexp.MatchString(path) {
-			return nil
-		}
+func Walk(ctx context.Context, cfg WalkConfig, do func(filePath string, file io.Reader) error) error {
+	cfg.Concurrency = max(1, cfg.Concurrency)
 
-		files = append(files, path)
-		return nil
-	})
+	ctx, cancelCause := context.WithCancelCause(ctx)
+	defer cancelCause(errors.New("walk default canceled"))
+	do = scanned(ctx, do)
+
+	jobs, err := collectJobs(ctx, cfg)
 	if err != nil {
 		return err
 	}
-	slices.Sort(files)
-	slices.Sort(archives)
+
+	units := groupJobs(cfg, jobs)
 
 	wg := &sync.WaitGroup{}
 
 	concurrency := make(chan struct{}, cfg.Concurrency)
+	seq := newSequencer(len(units), cfg.Done)
 
-	wg.Add(len(files))
-	for _, file := range files {
+	wg.Add(len(units))
+	for idx, u := range units {
 		exec := func() {
 			concurrency <- struct{}{}
 			defer func() {
@@ -86,17 +115,39 @@ func Walk(ctx context.Context, cfg WalkConfig, do func(filePath string, file io.
 				wg.Done()
 			}()
 
-			err := func(filePath string) error {
-				f, err := os.Open(filePath)
+			// the jobs of a unit are processed sequentially
+			g := newGrouper(cfg.GroupRegexp, u)
+			unitFilePaths := make([]string, 0, len(u.jobs))
+			for _, j := range u.jobs {
+				var (
+					filePaths []string
+					err       error
+				)
+				switch j.kind {
+				case jobFile:
+					fileDo := g.wrap("", do)
+					filePaths, err = walkCached(cfg.Cache, j.path, fileDo, func() ([]string, error) {
+						return walkFile(j.path, fileDo)
+					})
+				case jobArchive:
+					filePaths, err = walkCached(cfg.Cache, j.path, g.wrap(j.path, do), func() ([]string, error) {
+						return walkArchive(ctx, cfg, g, j.path, func(walkFunc archive.WalkFunc) error {
+							return archive.Walk(j.path, walkFunc)
+						}, do)
+					})
+				case jobStdin:
+					filePaths, err = walkStdin(ctx, cfg, g, do)
+				}
 				if err != nil {
-					return fmt.Errorf("failed to open file %s: %w", filePath, err)
+					cancelCause(fmt.Errorf("error while processing %s: %w", j.path, err))
+					return
 				}
-				defer f.Close()
+				unitFilePaths = append(unitFilePaths, filePaths...)
+			}
 
-				return do(filePath, f)
-			}(file)
+			err := seq.Done(idx, unitFilePaths...)
 			if err != nil {
-				cancelCause(fmt.Errorf("error while processinf file %s: %w", file, err))
+				cancelCause(err)
 				return
 			}
 		}
@@ -112,63 +163,332 @@ func Walk(ctx context.Context, cfg WalkConfig, do func(filePath string, file io.
 			}
 		}
 	}
+	wg.Wait()
 
-	wg.Add(len(archives))
-	for _, file := range archives {
-		exec := func() {
-			concurrency <- struct{}{}
-			defer func() {
-				<-concurrency
-				wg.Done()
-			}()
+	err = ctxutils.Done(ctx)
+	if err != nil {
+		return err
+	}
+	return nil
+}
 
-			err := archive.Walk(file, func(path string, info fs.FileInfo, r io.Reader, err error) error {
-				if err != nil {
-					return err
-				}
+// scanned counts every file that has been processed by do, including followed files that
+// are only processed until ctx is canceled.
+func scanned(ctx context.Context, do func(filePath string, file io.Reader) error) func(filePath string, file io.Reader) error {
+	return func(filePath string, file io.Reader) error {
+		defer diag.FileScanned(ctx, filePath)
+		return do(filePath, file)
+	}
+}
 
-				err = ctxutils.Done(ctx)
-				if err != nil {
-					return err
-				}
+// collectJobs returns the explicit inputs followed by the files and archives of the search dirs.
+func collectJobs(ctx context.Context, cfg WalkConfig) ([]job, error) {
+	jobs := make([]job, 0, 16)
+	for _, input := range cfg.Inputs {
+		if input == Stdin {
+			jobs = append(jobs, job{kind: jobStdin, path: Stdin})
+			continue
+		}
 
-				if !info.Mode().IsRegular() {
-					// skip dirs & symlinks
-					return nil
-				}
+		path, err := filepath.Abs(input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get absolute path of input %s: %w", input, err)
+		}
 
-				if cfg.FileRegexp != nil && !cfg.FileRegexp.MatchString(path) {
-					return nil
-				}
+		fi, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid input: %w", err)
+		}
 
-				filePath := fmt.Sprintf("%s@%s", file, path)
-				return do(filePath, r)
-			})
+		switch {
+		case fi.IsDir():
+			dirJobs, err := collect(ctx, cfg, path)
 			if err != nil {
-				if errors.Is(err, archive.ErrUnsupportedArchive) {
-					log.Printf("skipping unsupported archive: %s", file)
-					return
-				}
-				cancelCause(fmt.Errorf("failed to walk archive %s: %w", file, err))
+				return nil, err
 			}
+			jobs = append(jobs, dirJobs...)
+		case cfg.ArchiveRegexp != nil && cfg.ArchiveRegexp.MatchString(path):
+			jobs = append(jobs, job{kind: jobArchive, path: path})
+		default:
+			jobs = append(jobs, job{kind: jobFile, path: path})
 		}
+	}
 
-		if cfg.Concurrency > 1 {
-			// only run in parallel if concurrency is greater than 1
-			go exec()
-		} else {
-			exec()
-			err = ctxutils.Done(ctx)
+	for _, searchDir := range cfg.SearchDirs {
+		entryDir, err := filepath.Abs(searchDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get absolute path of root dir or file: %w", err)
+		}
+
+		dirJobs, err := collect(ctx, cfg, entryDir)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, dirJobs...)
+	}
+	return jobs, nil
+}
+
+// collect returns the log files of a directory followed by its archives, both in lexical order.
+func collect(ctx context.Context, cfg WalkConfig, dir string) ([]job, error) {
+	c := collector{
+		ctx:      ctx,
+		cfg:      cfg,
+		root:     dir,
+		files:    make([]string, 0, 16),
+		archives: make([]string, 0, 1),
+		visited:  make(map[string]bool, 1),
+	}
+
+	err := c.walk(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(c.files)
+	slices.Sort(c.archives)
+
+	jobs := make([]job, 0, len(c.files)+len(c.archives))
+	for _, file := range c.files {
+		jobs = append(jobs, job{kind: jobFile, path: file})
+	}
+	for _, file := range c.archives {
+		jobs = append(jobs, job{kind: jobArchive, path: file})
+	}
+	return jobs, nil
+}
+
+// collector collects the log file and archive paths of a directory tree.
+type collector struct {
+	ctx      context.Context
+	cfg      WalkConfig
+	root     string
+	files    []string
+	archives []string
+
+	// resolved paths of walked directories and collected files in order to
+	// prevent symlink loops and duplicates
+	visited map[string]bool
+}
+
+// walk collects the files of dir, which is located depth levels below the search dir.
+func (c *collector) walk(dir string, depth int) error {
+	if c.cfg.FollowSymlinks && !c.visit(dir) {
+		return nil
+	}
+
+	return filepath.WalkDir(dir, func(path string, info os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		err = ctxutils.Done(c.ctx)
+		if err != nil {
+			return err
+		}
+
+		if path == dir {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		level := depth + strings.Count(rel, string(filepath.Separator)) + 1
+
+		if excluded(c.cfg.ExcludeRegexp, c.root, path) {
+			if info.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if info.IsDir() {
+			if c.cfg.MaxDepth > 0 && level >= c.cfg.MaxDepth {
+				// files in this directory would exceed the max depth
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if info.Type()&fs.ModeSymlink != 0 {
+			if !c.cfg.FollowSymlinks {
+				return nil
+			}
+
+			fi, err := os.Stat(path)
 			if err != nil {
-				return err
+				slog.WarnContext(c.ctx, "skipping broken symlink", "file", path)
+				return nil
 			}
+			if fi.IsDir() {
+				if c.cfg.MaxDepth > 0 && level >= c.cfg.MaxDepth {
+					return nil
+				}
+				// the trailing separator makes WalkDir walk the target of the link
+				return c.walk(path+string(filepath.Separator), level)
+			}
+			if !fi.Mode().IsRegular() {
+				return nil
+			}
+		} else if !info.Type().IsRegular() {
+			// skip non-files
+			return nil
 		}
+
+		c.add(path)
+		return nil
+	})
+}
+
+func (c *collector) add(path string) {
+	isArchive := c.cfg.IncludeArchives && c.cfg.ArchiveRegexp != nil && c.cfg.ArchiveRegexp.MatchString(path)
+	if !isArchive && (c.cfg.FileRegexp == nil || !c.cfg.FileRegexp.MatchString(path)) {
+		return
 	}
-	wg.Wait()
 
-	err = ctxutils.Done(ctx)
+	if c.cfg.FollowSymlinks && !c.visit(path) {
+		// already collected via another link
+		return
+	}
+
+	if isArchive {
+		c.archives = append(c.archives, path)
+	} else {
+		c.files = append(c.files, path)
+	}
+}
+
+// visit returns false in case the resolved path has already been visited.
+func (c *collector) visit(path string) bool {
+	resolved, err := filepath.EvalSymlinks(path)
 	if err != nil {
-		return err
+		resolved = path
 	}
-	return nil
+	if c.visited[resolved] {
+		return false
+	}
+	c.visited[resolved] = true
+	return true
+}
+
+// excluded matches the path relative to root against the exclude regex, see WalkConfig.ExcludeRegexp.
+// An empty root means that the path is already relative, e.g. the path of an archive entry.
+func excluded(re *regexp.Regexp, root, path string) bool {
+	if re == nil {
+		return false
+	}
+	if root != "" {
+		rel, err := filepath.Rel(root, path)
+		if err == nil {
+			path = rel
+		}
+	}
+	return re.MatchString("/" + strings.TrimPrefix(filepath.ToSlash(path), "/"))
+}
+
+// walkCached asks the cache for the log files of the file or archive at path and only walks it in case it is not cached.
+func walkCached(
+	cache Cache,
+	path string,
+	do func(filePath string, file io.Reader) error,
+	walk func() ([]string, error),
+) ([]string, error) {
+	if cache == nil {
+		return walk()
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	filePaths, cached, err := cache.Cached(path, info, do)
+	if err != nil || cached {
+		return filePaths, err
+	}
+
+	filePaths, err = walk()
+	if err != nil {
+		return filePaths, err
+	}
+	return filePaths, cache.Walked(path, info, filePaths)
+}
+
+func walkFile(filePath string, do func(filePath string, file io.Reader) error) ([]string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file %s: %w", filePath, err)
+	}
+	defer f.Close()
+
+	return []string{filePath}, do(filePath, f)
+}
+
+// walkArchive calls do for every log file in the archive that is walked by walk.
+// Unsupported archives are skipped.
+// walkArchive passes the entries of an archive to do, which are assigned to their groups in case g is not nil.
+func walkArchive(
+	ctx context.Context,
+	cfg WalkConfig,
+	g *grouper,
+	file string,
+	walk func(archive.WalkFunc) error,
+	do func(filePath string, file io.Reader) error,
+) ([]string, error) {
+	filePaths := make([]string, 0, 1)
+
+	err := walk(func(path string, info fs.FileInfo, r io.Reader, err error) error {
+		if err != nil {
+			return err
+		}
+
+		err = ctxutils.Done(ctx)
+		if err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			// skip dirs & symlinks
+			return nil
+		}
+
+		if cfg.FileRegexp != nil && !cfg.FileRegexp.MatchString(path) {
+			return nil
+		}
+
+		if excluded(cfg.ExcludeRegexp, "", path) {
+			return nil
+		}
+
+		filePath := fmt.Sprintf("%s@%s", file, path)
+		filePaths = append(filePaths, filePath)
+		return do(filePath, g.entry(file, filePath, info.ModTime(), r))
+	})
+	if err != nil {
+		if !errors.Is(err, archive.ErrUnsupportedArchive) {
+			return nil, fmt.Errorf("failed to walk archive %s: %w", file, err)
+		}
+		diag.ArchiveSkipped(ctx, file)
+	}
+	return filePaths, nil
+}
+
+// walkStdin searches stdin either as a single log file or as archive.
+func walkStdin(ctx context.Context, cfg WalkConfig, g *grouper, do func(filePath string, file io.Reader) error) ([]string, error) {
+	if cfg.Stdin == nil {
+		return nil, errors.New("no stdin available")
+	}
+
+	extension, r, err := archive.DetectReader(cfg.Stdin)
+	if err != nil {
+		return nil, err
+	}
+	if extension == "" {
+		return []string{Stdin}, do(Stdin, r)
+	}
+
+	return walkArchive(ctx, cfg, g, Stdin, func(walkFunc archive.WalkFunc) error {
+		return archive.WalkReader(r, extension, walkFunc)
+	}, do)
 }
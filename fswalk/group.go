@@ -0,0 +1,204 @@
+package fswalk
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/jxsl13/twlog/match"
+)
+
+// number of lines that are searched for the first timestamp of a log file
+const timestampLines = 16
+
+// GroupKey returns the group of a file path as defined by WalkConfig.GroupRegexp.
+// Files inside of archives have the path of the archive as prefix.
+func GroupKey(groupRegexp *regexp.Regexp, filePath string) (string, bool) {
+	if groupRegexp == nil {
+		return "", false
+	}
+
+	matches := groupRegexp.FindStringSubmatch(filePath)
+	if len(matches) == 0 {
+		return "", false
+	}
+	if len(matches) > 1 {
+		return matches[1], true
+	}
+	return matches[0], true
+}
+
+// unit is a list of jobs that must be processed sequentially.
+type unit struct {
+	// group of the jobs, empty in case they are not grouped
+	group string
+	jobs  []job
+}
+
+// groupJobs returns units of jobs that must be processed sequentially.
+// Units are ordered by their first job, the jobs of a group are ordered chronologically.
+func groupJobs(cfg WalkConfig, jobs []job) []unit {
+	units := make([]unit, 0, len(jobs))
+	if cfg.GroupRegexp == nil {
+		for _, j := range jobs {
+			units = append(units, unit{jobs: []job{j}})
+		}
+		return units
+	}
+
+	groups := make(map[string]int, 8)
+	for _, j := range jobs {
+		key, ok := GroupKey(cfg.GroupRegexp, j.path)
+		if !ok || j.kind == jobStdin {
+			units = append(units, unit{jobs: []job{j}})
+			continue
+		}
+
+		idx, found := groups[key]
+		if !found {
+			groups[key] = len(units)
+			units = append(units, unit{group: key, jobs: []job{j}})
+			continue
+		}
+		units[idx].jobs = append(units[idx].jobs, j)
+	}
+
+	for _, u := range units {
+		if len(u.jobs) < 2 {
+			continue
+		}
+
+		times := make(map[string]time.Time, len(u.jobs))
+		for _, j := range u.jobs {
+			times[j.path] = startTime(j)
+		}
+		slices.SortStableFunc(u.jobs, func(a, b job) int {
+			return times[a.path].Compare(times[b.path])
+		})
+	}
+	return units
+}
+
+// Grouped is implemented by the files that Walk passes to its callback in case they belong to a group.
+// The files of a group are processed sequentially and in chronological order, which allows them to share state,
+// e.g. which client id belongs to which IP, see GroupOf.
+type Grouped interface {
+	io.Reader
+	Group() string
+}
+
+// GroupOf returns the group of a file that Walk passed to its callback.
+func GroupOf(file io.Reader) (string, bool) {
+	g, ok := file.(Grouped)
+	if !ok {
+		return "", false
+	}
+	return g.Group(), true
+}
+
+type groupedFile struct {
+	io.Reader
+	group string
+}
+
+func (f groupedFile) Group() string {
+	return f.group
+}
+
+// grouper assigns the log files of a unit to their groups.
+// Archives that are not grouped themselves are processed concurrently to other archives,
+// which is why their entries are only grouped with the other entries of the same archive.
+type grouper struct {
+	groupRegexp *regexp.Regexp
+	unit        string
+	// modification time of the previous archive entry of every group
+	previous map[string]time.Time
+	// number of archive entries of every group that were older than the previous entry
+	restarts map[string]int
+}
+
+func newGrouper(groupRegexp *regexp.Regexp, u unit) *grouper {
+	return &grouper{
+		groupRegexp: groupRegexp,
+		unit:        u.group,
+		previous:    make(map[string]time.Time, 1),
+		restarts:    make(map[string]int, 1),
+	}
+}
+
+// wrap assigns the files that are passed to do to their group, see file and entry.
+func (g *grouper) wrap(archivePath string, do func(filePath string, file io.Reader) error) func(filePath string, file io.Reader) error {
+	return func(filePath string, file io.Reader) error {
+		if archivePath == "" {
+			return do(filePath, g.file(file))
+		}
+		return do(filePath, g.entry(archivePath, filePath, time.Time{}, file))
+	}
+}
+
+// file returns a log file of the unit with its group.
+func (g *grouper) file(file io.Reader) io.Reader {
+	if g == nil || g.unit == "" {
+		return file
+	}
+	return groupedFile{Reader: file, group: g.unit}
+}
+
+// entry returns an archive entry with its group. The entries of an archive are read in the order of the archive,
+// the state of a group is not carried over to an entry that is older than the previous entry of the group.
+// A zero modification time is unknown and never starts a new group.
+func (g *grouper) entry(archivePath, filePath string, modTime time.Time, file io.Reader) io.Reader {
+	if g == nil {
+		return file
+	}
+
+	group := g.unit
+	if group == "" {
+		key, ok := GroupKey(g.groupRegexp, filePath)
+		if !ok {
+			return file
+		}
+		group = archivePath + "\x00" + key
+	}
+
+	if !modTime.IsZero() {
+		if previous, ok := g.previous[group]; ok && modTime.Before(previous) {
+			g.restarts[group]++
+		}
+		g.previous[group] = modTime
+	}
+	if n := g.restarts[group]; n > 0 {
+		group = fmt.Sprintf("%s\x00%d", group, n)
+	}
+	return groupedFile{Reader: file, group: group}
+}
+
+// startTime returns the first timestamp of a log file or the modification time of archives
+// and files without timestamps.
+func startTime(j job) time.Time {
+	f, err := os.Open(j.path)
+	if err != nil {
+		return time.Time{}
+	}
+	defer f.Close()
+
+	if j.kind == jobFile {
+		scanner := bufio.NewScanner(f)
+		for i := 0; i < timestampLines && scanner.Scan(); i++ {
+			t, ok := match.Timestamp(scanner.Text())
+			if ok {
+				return t
+			}
+		}
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return time.Time{}
+	}
+	return fi.ModTime()
+}
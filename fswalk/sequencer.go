@@ -0,0 +1,43 @@
+package fswalk
+
+import "sync"
+
+// sequencer calls done in index order, no matter in which order the indices are completed.
+type sequencer struct {
+	mu        sync.Mutex
+	done      func(filePaths ...string) error
+	completed [][]string
+	finished  []bool
+	next      int
+}
+
+func newSequencer(size int, done func(filePaths ...string) error) *sequencer {
+	return &sequencer{
+		done:      done,
+		completed: make([][]string, size),
+		finished:  make([]bool, size),
+	}
+}
+
+// Done marks the index as completed and calls done for all consecutive completed indices.
+func (s *sequencer) Done(idx int, filePaths ...string) error {
+	if s.done == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.completed[idx] = filePaths
+	s.finished[idx] = true
+
+	for s.next < len(s.finished) && s.finished[s.next] {
+		err := s.done(s.completed[s.next]...)
+		s.completed[s.next] = nil
+		s.next++
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
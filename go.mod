@@ -3,13 +3,18 @@ module github.com/jxsl13/twlog
 go 1.23.2
 
 require (
+	github.com/BurntSushi/toml v1.6.0
 	github.com/bodgit/sevenzip v1.6.0
+	github.com/cloudflare/ahocorasick v0.0.0-20240916140611-054963ec9396
 	github.com/gabriel-vasile/mimetype v1.4.7
 	github.com/jxsl13/cli-config-boilerplate v0.1.0
 	github.com/klauspost/compress v1.17.9
 	github.com/sorairolake/lzip-go v0.3.5
 	github.com/spf13/cobra v1.8.1
+	github.com/spf13/pflag v1.0.6
 	github.com/ulikunitz/xz v0.5.12
+	go.etcd.io/bbolt v1.4.3
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -35,9 +40,8 @@ require (
 	github.com/mitchellh/copystructure v1.2.0 // indirect
 	github.com/mitchellh/reflectwalk v1.0.2 // indirect
 	github.com/pierrec/lz4/v4 v4.1.21 // indirect
-	github.com/spf13/pflag v1.0.5 // indirect
 	go4.org v0.0.0-20200411211856-f5505b9728dd // indirect
 	golang.org/x/net v0.31.0 // indirect
-	golang.org/x/sys v0.27.0 // indirect
+	golang.org/x/sys v0.29.0 // indirect
 	golang.org/x/text v0.20.0 // indirect
 )
@@ -0,0 +1,200 @@
+package banlist
+
+import (
+	"fmt"
+	"io"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Entry is a single IP address or an IP range that should be banned.
+// Time is the most recent time the IP address was seen, if known.
+type Entry struct {
+	Prefix netip.Prefix
+	Time   time.Time
+}
+
+func (e Entry) IsSingleIP() bool {
+	return e.Prefix.IsSingleIP()
+}
+
+// String returns the plain IP address for single IP entries and the CIDR range otherwise.
+func (e Entry) String() string {
+	if e.IsSingleIP() {
+		return e.Prefix.Addr().String()
+	}
+	return e.Prefix.String()
+}
+
+// Collapse deduplicates the IP addresses and replaces them with their network in case
+// at least threshold addresses share the same IPv4 or IPv6 network.
+// A threshold of 0 disables collapsing.
+func Collapse(ips map[netip.Addr]time.Time, threshold, bitsV4, bitsV6 int) []Entry {
+	var (
+		networks = make(map[netip.Prefix][]netip.Addr, len(ips))
+		entries  = make([]Entry, 0, len(ips))
+	)
+
+	for addr := range ips {
+		bits := bitsV4
+		if addr.Is6() {
+			bits = bitsV6
+		}
+		network, err := addr.Prefix(bits)
+		if err != nil {
+			network = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		networks[network] = append(networks[network], addr)
+	}
+
+	for network, addrs := range networks {
+		if threshold > 0 && len(addrs) >= threshold {
+			entry := Entry{Prefix: network}
+			for _, addr := range addrs {
+				if ips[addr].After(entry.Time) {
+					entry.Time = ips[addr]
+				}
+			}
+			entries = append(entries, entry)
+			continue
+		}
+		for _, addr := range addrs {
+			entries = append(entries, Entry{
+				Prefix: netip.PrefixFrom(addr, addr.BitLen()),
+				Time:   ips[addr],
+			})
+		}
+	}
+
+	slices.SortFunc(entries, func(a, b Entry) int {
+		if c := a.Prefix.Addr().Compare(b.Prefix.Addr()); c != 0 {
+			return c
+		}
+		return a.Prefix.Bits() - b.Prefix.Bits()
+	})
+	return entries
+}
+
+// WriteDDNet writes DDNet and Teeworlds rcon commands that ban all entries.
+// They can be pasted into the remote console, sent via econ or saved as config file and executed with exec.
+// A duration of zero bans permanently.
+func WriteDDNet(w io.Writer, entries []Entry, d time.Duration, reason string) error {
+	minutes := int(d / time.Minute)
+	reason = sanitizeReason(reason)
+
+	for _, e := range entries {
+		var err error
+		if e.IsSingleIP() {
+			_, err = fmt.Fprintf(w, "ban %s %d %s\n", e, minutes, reason)
+		} else {
+			first, last := bounds(e.Prefix)
+			_, err = fmt.Fprintf(w, "ban_range %s %s %d %s\n", first, last, minutes, reason)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteNFTables writes an nftables table with one IPv4 and one IPv6 set that contain all entries.
+// The file can be loaded with nft -f and referenced in rules as @<name>_ipv4 and @<name>_ipv6.
+// A duration of zero creates sets without timeout.
+func WriteNFTables(w io.Writer, entries []Entry, d time.Duration, reason, name string) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n", sanitizeReason(reason))
+	fmt.Fprintf(&sb, "table inet %s {\n", name)
+	for _, family := range []string{"ipv4", "ipv6"} {
+		elements := make([]string, 0, len(entries))
+		for _, e := range entries {
+			if (family == "ipv6") == e.Prefix.Addr().Is6() {
+				elements = append(elements, e.String())
+			}
+		}
+
+		fmt.Fprintf(&sb, "\tset %s_%s {\n", name, family)
+		fmt.Fprintf(&sb, "\t\ttype %s_addr\n", family)
+		if d > 0 {
+			sb.WriteString("\t\tflags interval,timeout\n")
+			fmt.Fprintf(&sb, "\t\ttimeout %ds\n", int(d.Seconds()))
+		} else {
+			sb.WriteString("\t\tflags interval\n")
+		}
+		if len(elements) > 0 {
+			fmt.Fprintf(&sb, "\t\telements = { %s }\n", strings.Join(elements, ", "))
+		}
+		sb.WriteString("\t}\n")
+	}
+	sb.WriteString("}\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// WriteIPSet writes a file for ipset restore with one IPv4 and one IPv6 hash:net set that contain all entries.
+// A duration of zero creates sets without timeout.
+func WriteIPSet(w io.Writer, entries []Entry, d time.Duration, reason, name string) error {
+	timeout := ""
+	if d > 0 {
+		timeout = fmt.Sprintf(" timeout %d", int(d.Seconds()))
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n", sanitizeReason(reason))
+	fmt.Fprintf(&sb, "create %s-v4 hash:net family inet%s -exist\n", name, timeout)
+	fmt.Fprintf(&sb, "create %s-v6 hash:net family inet6%s -exist\n", name, timeout)
+	for _, e := range entries {
+		set := name + "-v4"
+		if e.Prefix.Addr().Is6() {
+			set = name + "-v6"
+		}
+		fmt.Fprintf(&sb, "add %s %s -exist\n", set, e)
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// WriteFail2Ban writes one log line per IP address that can be matched with the fail2ban filter
+//
+//	failregex = ^\S+ \S+ twlog: ban <HOST> reason=".*"$
+//
+// fail2ban expects single hosts, which is why IP ranges are written as their first address.
+func WriteFail2Ban(w io.Writer, entries []Entry, reason string) error {
+	now := time.Now()
+	reason = strings.ReplaceAll(sanitizeReason(reason), `"`, `'`)
+
+	for _, e := range entries {
+		t := e.Time
+		if t.IsZero() {
+			t = now
+		}
+		_, err := fmt.Fprintf(w, "%s twlog: ban %s reason=%q\n", t.Format(time.DateTime), e.Prefix.Addr(), reason)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bounds returns the first and the last address of a network.
+func bounds(prefix netip.Prefix) (first, last netip.Addr) {
+	return prefix.Masked().Addr(), lastAddr(prefix)
+}
+
+// lastAddr sets all host bits of the network to one.
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	b := prefix.Masked().Addr().AsSlice()
+	for i := prefix.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 1 << (7 - i%8)
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
+
+// sanitizeReason removes characters that would be interpreted by the DDNet console or break line based formats.
+func sanitizeReason(reason string) string {
+	return strings.NewReplacer(";", ",", "\n", " ", "\r", " ", "#", "").Replace(strings.TrimSpace(reason))
+}
@@ -0,0 +1,57 @@
+package banlist
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCollapse(t *testing.T) {
+	ips := map[netip.Addr]time.Time{
+		netip.MustParseAddr("1.2.3.4"):     {},
+		netip.MustParseAddr("1.2.3.5"):     {},
+		netip.MustParseAddr("1.2.4.1"):     {},
+		netip.MustParseAddr("2001:db8::1"): {},
+		netip.MustParseAddr("2001:db8::2"): {},
+	}
+
+	tests := []struct {
+		threshold int
+		expected  string
+	}{
+		{0, "1.2.3.4 1.2.3.5 1.2.4.1 2001:db8::1 2001:db8::2"},
+		{2, "1.2.3.0/24 1.2.4.1 2001:db8::/64"},
+		{3, "1.2.3.4 1.2.3.5 1.2.4.1 2001:db8::1 2001:db8::2"},
+	}
+
+	for _, test := range tests {
+		entries := Collapse(ips, test.threshold, 24, 64)
+		values := make([]string, 0, len(entries))
+		for _, e := range entries {
+			values = append(values, e.String())
+		}
+
+		if actual := strings.Join(values, " "); actual != test.expected {
+			t.Errorf("threshold %d: expected %q, got %q", test.threshold, test.expected, actual)
+		}
+	}
+}
+
+func TestWriteDDNet(t *testing.T) {
+	entries := []Entry{
+		{Prefix: netip.MustParsePrefix("1.2.3.4/32")},
+		{Prefix: netip.MustParsePrefix("5.6.0.0/16")},
+	}
+
+	var sb strings.Builder
+	err := WriteDDNet(&sb, entries, 90*time.Minute, "spam; say hi")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "ban 1.2.3.4 90 spam, say hi\nban_range 5.6.0.0 5.6.255.255 90 spam, say hi\n"
+	if sb.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, sb.String())
+	}
+}
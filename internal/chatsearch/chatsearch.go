@@ -0,0 +1,154 @@
+// Package chatsearch searches the chat messages of log files for the said commands.
+package chatsearch
+
+import (
+	"context"
+	"io"
+
+	"github.com/jxsl13/twlog/config"
+	"github.com/jxsl13/twlog/ctxutils"
+	"github.com/jxsl13/twlog/event"
+	"github.com/jxsl13/twlog/internal/diag"
+	"github.com/jxsl13/twlog/internal/dialect"
+	"github.com/jxsl13/twlog/internal/filter"
+	"github.com/jxsl13/twlog/internal/linecontext"
+	"github.com/jxsl13/twlog/internal/matcher"
+	"github.com/jxsl13/twlog/model"
+)
+
+// Field selects the part of a chat message that is matched against the search patterns.
+type Field func(e event.ChatEvent) string
+
+// Text matches the message of the chat line.
+func Text(e event.ChatEvent) string {
+	return e.Text
+}
+
+// Nickname matches the nickname of the player that wrote the chat line.
+func Nickname(e event.ChatEvent) string {
+	return e.Nickname
+}
+
+// Search returns all chat messages whose field matches one of the patterns.
+// playerMap contains the client id -> IP state, which may be carried over from previous log files.
+func Search(
+	ctx context.Context,
+	filePath string,
+	f io.Reader,
+	playerMap map[int]string,
+	field Field,
+	m matcher.Matcher,
+	cfg *config.SaidConfig,
+	where *filter.Expr,
+) (model.PlayerExtendedList, error) {
+	players := make(model.PlayerExtendedList, 0, 16)
+	err := Scan(ctx, filePath, f, playerMap, field, m, cfg, where, func(p ...model.PlayerExtended) error {
+		players = append(players, p...)
+		return nil
+	})
+	return players, err
+}
+
+// Scan calls emit for every chat message whose field matches one of the patterns as soon as it has been read.
+// Messages whose context lines overlap are emitted together.
+// playerMap contains the client id -> IP state, which is updated for as long as the reader provides data.
+func Scan(
+	ctx context.Context,
+	filePath string,
+	f io.Reader,
+	playerMap map[int]string,
+	field Field,
+	m matcher.Matcher,
+	cfg *config.SaidConfig,
+	where *filter.Expr,
+	emit func(...model.PlayerExtended) error,
+) error {
+	scanner := dialect.NewScanner(ctx, f)
+
+	// results are delayed until the lines after them have been read
+	var lines *linecontext.Collector[model.PlayerExtended]
+	if cfg.WithContext() {
+		scanner.KeepRawLines()
+		lines = linecontext.New(cfg.Before, cfg.After, func(players []model.PlayerExtended, contextLines model.ContextLines) error {
+			for i := range players {
+				players[i].Context = &contextLines
+			}
+			return emit(players...)
+		})
+	}
+
+	var err error
+	for scanner.Scan() {
+		err = ctxutils.Done(ctx)
+		if err != nil {
+			return err
+		}
+
+		if lines != nil {
+			err = lines.Lines(scanner.RawLines())
+			if err != nil {
+				return err
+			}
+		}
+
+		switch e := scanner.Event().(type) {
+		case event.JoinEvent:
+			playerMap[e.ID] = e.IP
+		case event.LeaveEvent:
+			delete(playerMap, e.ID)
+		case event.ChatEvent:
+			if !cfg.InChannel(e.Channel) {
+				continue
+			}
+
+			pattern, ok := m.Match(field(e))
+			if !ok {
+				continue
+			}
+
+			if !cfg.InTimeWindow(e.Time) {
+				continue
+			}
+
+			// the server does not have an IP
+			ip, ok := playerMap[e.ID]
+			if !ok && !e.FromServer() {
+				diag.OrphanChat(ctx, filePath, e.LineNumber, e.Nickname, e.ID)
+				continue
+			}
+
+			if !where.Matches(filter.ChatRecord(filePath, e, ip)) {
+				continue
+			}
+
+			p := model.NewPlayerExtended(filePath, e.Time, e.Nickname, e.ID, ip, e.Text)
+			if cfg.MultiplePatterns() {
+				p.Pattern = pattern
+			}
+			if lines != nil {
+				err = lines.Match(e.LineNumber, p)
+			} else {
+				err = emit(p)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	diag.ParseFailures(ctx, filePath, scanner.Malformed())
+
+	if lines != nil {
+		// trailing lines that do not contain any event
+		err = lines.Lines(scanner.RawLines())
+		if err != nil {
+			return err
+		}
+		err = lines.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,156 @@
+package diag
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+)
+
+type summaryKey struct{}
+
+// WithSummary returns a context that carries the summary of the current run.
+func WithSummary(ctx context.Context, s *Summary) context.Context {
+	return context.WithValue(ctx, summaryKey{}, s)
+}
+
+// FromContext returns the summary of the current run or nil.
+// All methods of a nil summary are no-ops.
+func FromContext(ctx context.Context) *Summary {
+	s, _ := ctx.Value(summaryKey{}).(*Summary)
+	return s
+}
+
+// FileSummary contains the diagnostics of a single log file.
+type FileSummary struct {
+	File string
+	// chat lines of players whose join line could not be found
+	OrphanChatLines int
+	// non-empty lines that neither contain a known event nor start with a log timestamp
+	ParseFailures int
+}
+
+// Summary collects diagnostics during a run in order to log them at the end of the run.
+type Summary struct {
+	mu              sync.Mutex
+	filesScanned    int
+	skippedArchives []string
+	files           map[string]*FileSummary
+}
+
+func NewSummary() *Summary {
+	return &Summary{
+		files: make(map[string]*FileSummary, 16),
+	}
+}
+
+func (s *Summary) file(filePath string) *FileSummary {
+	fs, ok := s.files[filePath]
+	if !ok {
+		fs = &FileSummary{File: filePath}
+		s.files[filePath] = fs
+	}
+	return fs
+}
+
+// FileScanned counts log files that have been searched.
+func FileScanned(ctx context.Context, filePath string) {
+	slog.DebugContext(ctx, "scanned file", "file", filePath)
+
+	s := FromContext(ctx)
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.filesScanned++
+}
+
+// ArchiveSkipped logs and counts archives that are not supported.
+func ArchiveSkipped(ctx context.Context, filePath string) {
+	slog.WarnContext(ctx, "skipping unsupported archive", "file", filePath)
+
+	s := FromContext(ctx)
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.skippedArchives = append(s.skippedArchives, filePath)
+}
+
+// OrphanChat logs and counts chat lines of players whose join line could not be found.
+func OrphanChat(ctx context.Context, filePath string, lineNumber int, nickname string, id int) {
+	slog.DebugContext(ctx, "could not find join line",
+		"file", filePath,
+		"line", lineNumber,
+		"nickname", nickname,
+		"id", id,
+	)
+
+	s := FromContext(ctx)
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.file(filePath).OrphanChatLines++
+}
+
+// ParseFailures counts the malformed lines of a log file.
+func ParseFailures(ctx context.Context, filePath string, n int) {
+	s := FromContext(ctx)
+	if s == nil || n == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.file(filePath).ParseFailures += n
+}
+
+// Log logs the summary as a single record and one record per log file with problems.
+// The summary is logged at debug level unless archives were skipped or lines could not be attributed or parsed.
+// Nothing is logged in case no files have been searched.
+func (s *Summary) Log(ctx context.Context, logger *slog.Logger) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.filesScanned == 0 && len(s.skippedArchives) == 0 {
+		return
+	}
+
+	files := make([]*FileSummary, 0, len(s.files))
+	var orphans, failures int
+	for _, fs := range s.files {
+		files = append(files, fs)
+		orphans += fs.OrphanChatLines
+		failures += fs.ParseFailures
+	}
+	slices.SortFunc(files, func(a, b *FileSummary) int {
+		return strings.Compare(a.File, b.File)
+	})
+
+	for _, fs := range files {
+		logger.InfoContext(ctx, "file summary",
+			"file", fs.File,
+			"orphan_chat_lines", fs.OrphanChatLines,
+			"parse_failures", fs.ParseFailures,
+		)
+	}
+
+	level := slog.LevelDebug
+	if len(s.skippedArchives) > 0 || orphans > 0 || failures > 0 {
+		level = slog.LevelInfo
+	}
+
+	logger.Log(ctx, level, "summary",
+		"files_scanned", s.filesScanned,
+		"archives_skipped", len(s.skippedArchives),
+		"orphan_chat_lines", orphans,
+		"parse_failures", failures,
+	)
+}
@@ -0,0 +1,29 @@
+package diag
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSummaryLogLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	s := NewSummary()
+	ctx := WithSummary(context.Background(), s)
+	FileScanned(ctx, "a.log")
+
+	s.Log(ctx, logger)
+	if buf.Len() != 0 {
+		t.Fatalf("expected a clean run to be logged at debug level, got %q", buf.String())
+	}
+
+	ParseFailures(ctx, "a.log", 2)
+	s.Log(ctx, logger)
+	if !strings.Contains(buf.String(), "msg=summary") || !strings.Contains(buf.String(), "parse_failures=2") {
+		t.Fatalf("expected the summary to be logged at info level, got %q", buf.String())
+	}
+}
@@ -0,0 +1,325 @@
+// Package dialect contains the log dialects of server forks whose log lines differ from the built-in ones.
+//
+// A dialect defines regular expressions with named capture groups for each kind of event.
+// Lines that none of the expressions of a dialect match are parsed by the built-in parser,
+// which is why a dialect only needs to define the lines that differ.
+//
+//	dialects:
+//	  - name: myfork
+//	    detect: 'myfork v\d+'
+//	    join: 'client joined id=(?P<id>\d+) addr=(?P<ip>\S+) name=''(?P<name>.*)'''
+//	    chat: '\[chat\]: (?P<name>.+) \((?P<id>\d+)\): (?P<text>.+)'
+package dialect
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jxsl13/twlog/event"
+	"github.com/jxsl13/twlog/match"
+)
+
+// Builtin is the name of the built-in dialect, which supports DDNet, vanilla and zCatch servers.
+const Builtin = "builtin"
+
+// Definition is a dialect as it is defined in a dialect file.
+// Every expression is optional, the named capture groups that each expression
+// must or may contain are listed next to it.
+type Definition struct {
+	Name string `yaml:"name" toml:"name"`
+	// Detect identifies the lines of this dialect during the auto-detection,
+	// all other expressions are used in case it is empty.
+	Detect string `yaml:"detect" toml:"detect"`
+
+	// id, ip, optional: port, name, clan, country, version
+	Join string `yaml:"join" toml:"join"`
+	// id, optional: ip, reason
+	Leave string `yaml:"leave" toml:"leave"`
+	// id, text, optional: name, team, target (whisper receiver), channel (all, team, whisper or server)
+	Chat string `yaml:"chat" toml:"chat"`
+	// old, new
+	NameChange string `yaml:"name_change" toml:"name_change"`
+	// map
+	MapChange string `yaml:"map_change" toml:"map_change"`
+	// name
+	Enter string `yaml:"enter" toml:"enter"`
+	// id, name, optional: team
+	TeamJoin string `yaml:"team_join" toml:"team_join"`
+}
+
+// Dialect is a compiled dialect definition.
+type Dialect struct {
+	name   string
+	detect *regexp.Regexp
+
+	join       *regexp.Regexp
+	leave      *regexp.Regexp
+	chat       *regexp.Regexp
+	nameChange *regexp.Regexp
+	mapChange  *regexp.Regexp
+	enter      *regexp.Regexp
+	teamJoin   *regexp.Regexp
+}
+
+// Compile validates the expressions of a definition and their capture groups.
+func Compile(def Definition) (*Dialect, error) {
+	name := strings.TrimSpace(def.Name)
+	if name == "" {
+		return nil, fmt.Errorf("dialect without name")
+	}
+	if strings.EqualFold(name, Builtin) || strings.EqualFold(name, Auto) {
+		return nil, fmt.Errorf("dialect name %q is reserved", name)
+	}
+
+	var (
+		d   = &Dialect{name: name}
+		err error
+	)
+	compile := func(key, expr string, required, optional []string) *regexp.Regexp {
+		if err != nil || expr == "" {
+			return nil
+		}
+		var re *regexp.Regexp
+		re, err = compilePattern(expr, required, optional)
+		if err != nil {
+			err = fmt.Errorf("dialect %s: invalid %s expression: %w", name, key, err)
+		}
+		return re
+	}
+
+	d.detect = compile("detect", def.Detect, nil, nil)
+	d.join = compile("join", def.Join, []string{"id", "ip"}, []string{"port", "name", "clan", "country", "version"})
+	d.leave = compile("leave", def.Leave, []string{"id"}, []string{"ip", "reason"})
+	d.chat = compile("chat", def.Chat, []string{"id", "text"}, []string{"name", "team", "target", "channel"})
+	d.nameChange = compile("name_change", def.NameChange, []string{"old", "new"}, nil)
+	d.mapChange = compile("map_change", def.MapChange, []string{"map"}, nil)
+	d.enter = compile("enter", def.Enter, []string{"name"}, nil)
+	d.teamJoin = compile("team_join", def.TeamJoin, []string{"id", "name"}, []string{"team"})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(d.patterns()) == 0 {
+		return nil, fmt.Errorf("dialect %s does not define any expression", name)
+	}
+	return d, nil
+}
+
+// compilePattern compiles an expression that must contain the required named groups
+// and must not contain any unknown named groups.
+func compilePattern(expr string, required, optional []string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	names := re.SubexpNames()
+	for _, r := range required {
+		if re.SubexpIndex(r) < 0 {
+			return nil, fmt.Errorf("missing named group (?P<%s>...)", r)
+		}
+	}
+	for _, n := range names[1:] {
+		if n != "" && !slices.Contains(required, n) && !slices.Contains(optional, n) {
+			return nil, fmt.Errorf("unknown named group %q, must be one of %v", n, append(required, optional...))
+		}
+	}
+	return re, nil
+}
+
+// Name returns the name of the dialect.
+func (d *Dialect) Name() string {
+	return d.name
+}
+
+// patterns returns all event expressions that are defined.
+func (d *Dialect) patterns() []*regexp.Regexp {
+	patterns := make([]*regexp.Regexp, 0, 7)
+	for _, re := range []*regexp.Regexp{d.join, d.leave, d.chat, d.nameChange, d.mapChange, d.enter, d.teamJoin} {
+		if re != nil {
+			patterns = append(patterns, re)
+		}
+	}
+	return patterns
+}
+
+// Matches reports whether a line belongs to this dialect.
+func (d *Dialect) Matches(line string) bool {
+	if d.detect != nil {
+		return d.detect.MatchString(line)
+	}
+	for _, re := range d.patterns() {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse turns a log line into an event and falls back to the built-in parser
+// in case none of the expressions of the dialect match.
+func (d *Dialect) Parse(lineNumber int, line string, names match.Names) (event.Event, bool) {
+	meta := event.Meta{
+		LineNumber: lineNumber,
+		Line:       line,
+	}
+	meta.Time, _ = match.Timestamp(line)
+
+	if g, ok := groups(d.join, line); ok {
+		return d.joinEvent(meta, g)
+	} else if g, ok := groups(d.leave, line); ok {
+		return d.leaveEvent(meta, g)
+	} else if g, ok := groups(d.nameChange, line); ok {
+		return event.NameChangeEvent{Meta: meta, ID: -1, OldName: g["old"], NewName: g["new"]}, true
+	} else if g, ok := groups(d.enter, line); ok {
+		return event.EnterEvent{Meta: meta, ID: -1, Nickname: g["name"]}, true
+	} else if g, ok := groups(d.teamJoin, line); ok {
+		return d.teamJoinEvent(meta, g)
+	} else if g, ok := groups(d.chat, line); ok {
+		return d.chatEvent(meta, g)
+	} else if g, ok := groups(d.mapChange, line); ok {
+		return event.MapChangeEvent{Meta: meta, Map: g["map"]}, true
+	}
+
+	return event.ParseWithNames(lineNumber, line, names)
+}
+
+// groups returns the named groups of the first match.
+func groups(re *regexp.Regexp, line string) (map[string]string, bool) {
+	if re == nil {
+		return nil, false
+	}
+	matches := re.FindStringSubmatch(line)
+	if matches == nil {
+		return nil, false
+	}
+
+	g := make(map[string]string, len(matches))
+	for i, name := range re.SubexpNames() {
+		if name != "" && matches[i] != "" {
+			g[name] = matches[i]
+		}
+	}
+	return g, true
+}
+
+// atoi parses an optional integer group and returns the default value in case it is missing.
+// Groups that do not contain a number do not match the event.
+func atoi(g map[string]string, name string, defaultValue int) (int, bool) {
+	s, ok := g[name]
+	if !ok {
+		return defaultValue, true
+	}
+	i, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0, false
+	}
+	return i, true
+}
+
+func (d *Dialect) joinEvent(meta event.Meta, g map[string]string) (event.Event, bool) {
+	id, ok := atoi(g, "id", -1)
+	if !ok {
+		return nil, false
+	}
+	ip, port, ok := match.SplitAddr(g["ip"])
+	if !ok {
+		return nil, false
+	}
+	if p, ok := atoi(g, "port", port); ok {
+		port = p
+	}
+	version, _ := atoi(g, "version", 0)
+	country, _ := atoi(g, "country", -1)
+
+	return event.JoinEvent{
+		Meta:     meta,
+		ID:       id,
+		IP:       ip,
+		Port:     port,
+		Version:  version,
+		Nickname: g["name"],
+		Clan:     g["clan"],
+		Country:  country,
+	}, true
+}
+
+func (d *Dialect) leaveEvent(meta event.Meta, g map[string]string) (event.Event, bool) {
+	id, ok := atoi(g, "id", -1)
+	if !ok {
+		return nil, false
+	}
+
+	ip := ""
+	if addr, ok := g["ip"]; ok {
+		ip, _, ok = match.SplitAddr(addr)
+		if !ok {
+			return nil, false
+		}
+	}
+	return event.LeaveEvent{Meta: meta, ID: id, IP: ip, Reason: g["reason"]}, true
+}
+
+func (d *Dialect) teamJoinEvent(meta event.Meta, g map[string]string) (event.Event, bool) {
+	id, ok := atoi(g, "id", -1)
+	if !ok {
+		return nil, false
+	}
+	team, ok := atoi(g, "team", 0)
+	if !ok {
+		return nil, false
+	}
+	return event.TeamJoinEvent{Meta: meta, ID: id, Nickname: g["name"], Team: team}, true
+}
+
+// teamAll is the team field of messages to all players.
+const teamAll = -2
+
+func (d *Dialect) chatEvent(meta event.Meta, g map[string]string) (event.Event, bool) {
+	id, ok := atoi(g, "id", -1)
+	if !ok {
+		return nil, false
+	}
+	team, ok := atoi(g, "team", teamAll)
+	if !ok {
+		return nil, false
+	}
+	target, ok := atoi(g, "target", -1)
+	if !ok {
+		return nil, false
+	}
+
+	var channel match.Channel
+	switch c := strings.ToLower(g["channel"]); {
+	case c == "teamchat":
+		channel = match.ChannelTeam
+	case c == "chat":
+		channel = match.ChannelAll
+	case c != "":
+		channel = match.Channel(c)
+		if !slices.Contains(match.Channels, channel) {
+			return nil, false
+		}
+	case id < 0:
+		channel = match.ChannelServer
+	case target >= 0:
+		channel = match.ChannelWhisper
+	case team != teamAll:
+		channel = match.ChannelTeam
+	default:
+		channel = match.ChannelAll
+	}
+
+	return event.ChatEvent{
+		Meta:     meta,
+		ID:       id,
+		Nickname: g["name"],
+		Text:     g["text"],
+		Channel:  channel,
+		Team:     team,
+		Target:   target,
+	}, true
+}
@@ -0,0 +1,135 @@
+package dialect
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jxsl13/twlog/event"
+)
+
+var forkDefinition = Definition{
+	Name:   "fork",
+	Detect: `\[fork\]`,
+	Join:   `\[fork\] join (?P<id>\d+) (?P<ip>\S+) '(?P<name>.*)'$`,
+	Chat:   `\[fork\] (?P<name>.+) \((?P<id>\d+)(?:->(?P<target>\d+))?\): (?P<text>.+)$`,
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name string
+		def  Definition
+		err  string
+	}{
+		{"valid", forkDefinition, ""},
+		{"no name", Definition{Join: `(?P<id>\d+) (?P<ip>\S+)`}, "without name"},
+		{"reserved name", Definition{Name: "builtin", Join: `(?P<id>\d+) (?P<ip>\S+)`}, "reserved"},
+		{"no expressions", Definition{Name: "empty", Detect: "x"}, "does not define any expression"},
+		{"missing group", Definition{Name: "x", Join: `(?P<id>\d+)`}, "missing named group (?P<ip>...)"},
+		{"unknown group", Definition{Name: "x", Leave: `(?P<id>\d+) (?P<nick>.+)`}, `unknown named group "nick"`},
+		{"invalid regex", Definition{Name: "x", Chat: `(?P<id>\d+`}, "invalid chat expression"},
+	}
+
+	for _, test := range tests {
+		_, err := Compile(test.def)
+		if test.err == "" && err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		} else if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("%s: expected error containing %q, got %v", test.name, test.err, err)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	d, err := Compile(forkDefinition)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, ok := d.Parse(1, "2024-05-01 18:00:00 [fork] join 3 1.2.3.4:8303 'a: b'", nil)
+	if j, isJoin := e.(event.JoinEvent); !ok || !isJoin || j.ID != 3 || j.IP != "1.2.3.4" || j.Port != 8303 || j.Nickname != "a: b" || j.Country != -1 || j.Time.IsZero() {
+		t.Errorf("expected join event, got %#v", e)
+	}
+
+	e, ok = d.Parse(2, "2024-05-01 18:00:01 [fork] a: b (3->5): psst", nil)
+	if c, isChat := e.(event.ChatEvent); !ok || !isChat || c.ID != 3 || c.Nickname != "a: b" || c.Text != "psst" || c.Channel != "whisper" || c.Target != 5 {
+		t.Errorf("expected whisper chat event, got %#v", e)
+	}
+
+	// lines that the dialect does not define are parsed by the built-in parser
+	e, ok = d.Parse(3, "[66326cf0][server]: client dropped. id=3 addr=1.2.3.4:8303 reason='Timeout'", nil)
+	if l, isLeave := e.(event.LeaveEvent); !ok || !isLeave || l.ID != 3 {
+		t.Errorf("expected built-in leave event, got %#v", e)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	r := NewRegistry()
+	err := r.Add(forkDefinition, Definition{Name: "other", Chat: `other (?P<id>\d+): (?P<text>.+)`})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		lines    []string
+		expected string
+	}{
+		{[]string{"2024-05-01 18:00:00 I chat: 0:-2:a: b"}, Builtin},
+		{[]string{"[fork] start", "other 1: hi", "[fork] x (1): y"}, "fork"},
+		{[]string{"other 1: hi"}, "other"},
+	}
+	for _, test := range tests {
+		name := Builtin
+		if d, ok := r.Detect(test.lines).(*Dialect); ok {
+			name = d.Name()
+		}
+		if name != test.expected {
+			t.Errorf("Detect(%q) = %s, expected %s", test.lines, name, test.expected)
+		}
+	}
+
+	err = r.Add(Definition{Name: "FORK", Enter: `(?P<name>.+) entered`})
+	if err == nil {
+		t.Errorf("expected duplicate dialect error")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"dialects.yaml": "dialects:\n  - name: fork\n    chat: '\\[fork\\] (?P<name>.+) \\((?P<id>\\d+)\\): (?P<text>.+)$'\n",
+		"dialects.toml": "[[dialects]]\nname = 'fork'\nchat = '''\\[fork\\] (?P<name>.+) \\((?P<id>\\d+)\\): (?P<text>.+)$'''\n",
+		"unknown.yaml":  "dialects:\n  - name: fork\n    chatt: 'x'\n",
+		"unknown.toml":  "[[dialects]]\nname = 'fork'\nchatt = 'x'\n",
+		"dialects.json": "{}",
+	}
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for name := range files {
+		r := NewRegistry()
+		err := r.Load(filepath.Join(dir, name))
+		valid := strings.HasPrefix(name, "dialects.") && !strings.HasSuffix(name, ".json")
+		if valid != (err == nil) {
+			t.Errorf("%s: unexpected result: %v", name, err)
+			continue
+		}
+		if !valid {
+			continue
+		}
+
+		p, ok := r.Parser("fork")
+		if !ok {
+			t.Fatalf("%s: missing dialect fork", name)
+		}
+		e, ok := p.Parse(1, "[fork] nick (2): hello", nil)
+		if c, isChat := e.(event.ChatEvent); !ok || !isChat || c.Nickname != "nick" || c.ID != 2 || c.Channel != "all" {
+			t.Errorf("%s: expected chat event, got %#v", name, e)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package dialect
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/jxsl13/twlog/event"
+	"github.com/jxsl13/twlog/match"
+	"gopkg.in/yaml.v3"
+)
+
+// Auto chooses the dialect of each file based on its first lines.
+const Auto = "auto"
+
+// File is the content of a dialect file.
+type File struct {
+	Dialects []Definition `yaml:"dialects" toml:"dialects"`
+}
+
+// Registry contains the built-in dialect and the dialects of a dialect file.
+type Registry struct {
+	dialects []*Dialect
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		dialects: make([]*Dialect, 0, 4),
+	}
+}
+
+// Load reads the dialects of a YAML (.yaml, .yml) or TOML (.toml) file.
+func (r *Registry) Load(filePath string) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read dialect file: %w", err)
+	}
+
+	var f File
+	switch ext := strings.ToLower(filepath.Ext(filePath)); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&f)
+		if err == io.EOF {
+			err = nil
+		}
+	case ".toml":
+		var md toml.MetaData
+		md, err = toml.Decode(string(data), &f)
+		if err == nil && len(md.Undecoded()) > 0 {
+			err = fmt.Errorf("unknown keys %v", md.Undecoded())
+		}
+	default:
+		return fmt.Errorf("unsupported dialect file extension %q: must be one of .yaml, .yml or .toml", ext)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid dialect file %s: %w", filePath, err)
+	}
+
+	if len(f.Dialects) == 0 {
+		return fmt.Errorf("dialect file %s does not define any dialects", filePath)
+	}
+	return r.Add(f.Dialects...)
+}
+
+// Add compiles and registers dialect definitions.
+func (r *Registry) Add(defs ...Definition) error {
+	for _, def := range defs {
+		d, err := Compile(def)
+		if err != nil {
+			return err
+		}
+		if _, ok := r.dialect(d.Name()); ok {
+			return fmt.Errorf("dialect %s is defined more than once", d.Name())
+		}
+		r.dialects = append(r.dialects, d)
+	}
+	return nil
+}
+
+// Names returns the names of all dialects including the built-in one.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.dialects)+1)
+	names = append(names, Builtin)
+	for _, d := range r.dialects {
+		names = append(names, d.Name())
+	}
+	return names
+}
+
+func (r *Registry) dialect(name string) (*Dialect, bool) {
+	for _, d := range r.dialects {
+		if strings.EqualFold(d.Name(), name) {
+			return d, true
+		}
+	}
+	return nil, false
+}
+
+// Parser returns the parser of a dialect.
+func (r *Registry) Parser(name string) (event.Parser, bool) {
+	if strings.EqualFold(name, Builtin) {
+		return event.ParserFunc(event.ParseWithNames), true
+	}
+	d, ok := r.dialect(name)
+	if !ok {
+		return nil, false
+	}
+	return d, true
+}
+
+// Detect returns the dialect that matches most of the lines.
+// In case of a tie the dialect that was defined first wins and in case no dialect
+// matches any line the built-in dialect is used.
+func (r *Registry) Detect(lines []string) event.Parser {
+	var (
+		best      event.Parser = event.ParserFunc(event.ParseWithNames)
+		bestCount              = 0
+	)
+	for _, d := range r.dialects {
+		count := 0
+		for _, line := range lines {
+			if d.Matches(line) {
+				count++
+			}
+		}
+		if count > bestCount {
+			best = d
+			bestCount = count
+		}
+	}
+	return best
+}
+
+// Parse is the parser of the auto-detection in follow mode, which parses each line with the first dialect
+// whose expressions match the line and falls back to the built-in dialect.
+// The detect expressions are not used, because they usually only match a few lines.
+func (r *Registry) Parse(lineNumber int, line string, names match.Names) (event.Event, bool) {
+	for _, d := range r.dialects {
+		for _, re := range d.patterns() {
+			if re.MatchString(line) {
+				return d.Parse(lineNumber, line, names)
+			}
+		}
+	}
+	return event.ParseWithNames(lineNumber, line, names)
+}
+
+// Selection is the dialect that is used for all log files.
+type Selection struct {
+	Registry *Registry
+	// Name is the name of a dialect or Auto
+	Name string
+	// DetectLines is the number of lines that the auto-detection looks at
+	DetectLines int
+	// Follow is set in follow mode, in which the first lines may take arbitrarily long to arrive,
+	// which is why the auto-detection chooses the dialect of each line instead of reading ahead.
+	Follow bool
+}
+
+// Apply configures the parser of a scanner.
+func (s *Selection) Apply(scanner *event.Scanner) {
+	if s == nil || s.Registry == nil || len(s.Registry.dialects) == 0 {
+		return
+	}
+
+	if strings.EqualFold(s.Name, Auto) {
+		if s.Follow {
+			scanner.SetParser(s.Registry)
+			return
+		}
+		scanner.DetectParser(s.DetectLines, s.Registry.Detect)
+		return
+	}
+	if p, ok := s.Registry.Parser(s.Name); ok {
+		scanner.SetParser(p)
+	}
+}
+
+type selectionKey struct{}
+
+// WithSelection returns a context that contains the dialect selection.
+func WithSelection(ctx context.Context, s *Selection) context.Context {
+	return context.WithValue(ctx, selectionKey{}, s)
+}
+
+// NewScanner returns an event scanner that parses the lines with the dialect selected in the context.
+func NewScanner(ctx context.Context, r io.Reader) *event.Scanner {
+	scanner := event.NewScanner(r)
+	s, _ := ctx.Value(selectionKey{}).(*Selection)
+	s.Apply(scanner)
+	return scanner
+}
@@ -0,0 +1,371 @@
+// Package filter implements the filter expression language of the --where flag.
+//
+//	nick =~ "bot" && text =~ 'discord\.gg' && ip in 1.2.0.0/16 && time > 2024-05-01
+//
+// An expression combines predicates with &&, || and !, which may be grouped with parentheses.
+// A predicate compares a field with a value:
+//
+//	nick, text, file   =~ and !~ (regex), == and != (exact)
+//	ip                 in (IP or CIDR range), =~ and !~ (regex), == and !=
+//	time               ==, !=, <, <=, > and >= (e.g. 2024-05-01, '2024-05-01 18:00' or now)
+//
+// Values that contain whitespace or parentheses must be quoted with double or single quotes.
+// A backslash only escapes the quote character, which keeps regular expressions readable.
+package filter
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jxsl13/twlog/config"
+	"github.com/jxsl13/twlog/event"
+)
+
+// Record contains the fields of a log event or result that an expression can refer to.
+// A player may be known by several nicknames, e.g. during a session, in which case a
+// nickname predicate matches as soon as one of them matches.
+type Record struct {
+	File      string
+	Time      time.Time
+	IP        string
+	Nicknames []string
+	Text      string
+}
+
+// ChatRecord returns the fields of a chat message of the player with the given IP.
+func ChatRecord(filePath string, e event.ChatEvent, ip string) Record {
+	return Record{
+		File:      filePath,
+		Time:      e.Time,
+		IP:        ip,
+		Nicknames: []string{e.Nickname},
+		Text:      e.Text,
+	}
+}
+
+// Expr is a compiled filter expression.
+type Expr struct {
+	src  string
+	root node
+}
+
+// Compile parses a filter expression.
+func Compile(src string) (*Expr, error) {
+	p := &parser{src: src}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return nil, p.errorf("unexpected %q", p.src[p.pos:])
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+// Matches reports whether the record passes the filter.
+// A nil expression matches every record.
+func (e *Expr) Matches(r Record) bool {
+	if e == nil {
+		return true
+	}
+	return e.root.eval(r)
+}
+
+func (e *Expr) String() string {
+	if e == nil {
+		return ""
+	}
+	return e.src
+}
+
+type node interface {
+	eval(r Record) bool
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(r Record) bool { return n.left.eval(r) && n.right.eval(r) }
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(r Record) bool { return n.left.eval(r) || n.right.eval(r) }
+
+type notNode struct{ node node }
+
+func (n notNode) eval(r Record) bool { return !n.node.eval(r) }
+
+// predicateFunc evaluates a single comparison.
+type predicateFunc func(r Record) bool
+
+func (f predicateFunc) eval(r Record) bool { return f(r) }
+
+type parser struct {
+	src string
+	pos int
+}
+
+func (p *parser) errorf(format string, a ...any) error {
+	return fmt.Errorf("invalid where expression at position %d: %s", p.pos+1, fmt.Sprintf(format, a...))
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+}
+
+// consume skips the token in case it is next.
+func (p *parser) consume(token string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(p.src[p.pos:], token) {
+		p.pos += len(token)
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.consume("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.consume("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.consume("!") {
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{n}, nil
+	}
+
+	if p.consume("(") {
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume(")") {
+			return nil, p.errorf("missing closing parenthesis")
+		}
+		return n, nil
+	}
+
+	return p.parsePredicate()
+}
+
+// operators sorted by length, so that <= is not parsed as <
+var operators = []string{"=~", "!~", "==", "!=", "<=", ">=", "<", ">", "in"}
+
+func (p *parser) parsePredicate() (node, error) {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.src) && (unicode.IsLetter(rune(p.src[p.pos])) || p.src[p.pos] == '_') {
+		p.pos++
+	}
+	field := strings.ToLower(p.src[start:p.pos])
+	if field == "" {
+		if p.pos >= len(p.src) {
+			return nil, p.errorf("unexpected end of expression, expected a field")
+		}
+		return nil, p.errorf("expected a field, got %q", p.src[p.pos:])
+	}
+
+	op := ""
+	for _, o := range operators {
+		if p.consume(o) {
+			op = o
+			break
+		}
+	}
+	if op == "" {
+		return nil, p.errorf("expected an operator after field %s, one of %v", field, operators)
+	}
+
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	pred, err := newPredicate(field, op, value)
+	if err != nil {
+		return nil, p.errorf("%v", err)
+	}
+	return pred, nil
+}
+
+// parseValue parses a quoted value or a bare value that ends at whitespace or a closing parenthesis.
+func (p *parser) parseValue() (string, error) {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return "", p.errorf("unexpected end of expression, expected a value")
+	}
+
+	quote := p.src[p.pos]
+	if quote != '"' && quote != '\'' {
+		start := p.pos
+		for p.pos < len(p.src) && !unicode.IsSpace(rune(p.src[p.pos])) && p.src[p.pos] != ')' {
+			p.pos++
+		}
+		if start == p.pos {
+			return "", p.errorf("expected a value")
+		}
+		return p.src[start:p.pos], nil
+	}
+
+	var sb strings.Builder
+	for i := p.pos + 1; i < len(p.src); i++ {
+		c := p.src[i]
+		switch {
+		case c == '\\' && i+1 < len(p.src) && p.src[i+1] == quote:
+			sb.WriteByte(quote)
+			i++
+		case c == quote:
+			p.pos = i + 1
+			return sb.String(), nil
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", p.errorf("missing closing quote %c", quote)
+}
+
+func newPredicate(field, op, value string) (node, error) {
+	switch field {
+	case "nick", "nickname", "name":
+		return stringPredicate(field, op, value, func(r Record) []string { return r.Nicknames })
+	case "text":
+		return stringPredicate(field, op, value, func(r Record) []string { return []string{r.Text} })
+	case "file":
+		return stringPredicate(field, op, value, func(r Record) []string { return []string{r.File} })
+	case "ip":
+		return ipPredicate(op, value)
+	case "time":
+		return timePredicate(op, value)
+	default:
+		return nil, fmt.Errorf("unknown field %q: must be one of nick, text, ip, time or file", field)
+	}
+}
+
+func stringPredicate(field, op, value string, values func(Record) []string) (node, error) {
+	var match func(string) bool
+	switch op {
+	case "=~", "!~":
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s regex: %w", field, err)
+		}
+		match = re.MatchString
+	case "==", "!=":
+		match = func(s string) bool { return s == value }
+	default:
+		return nil, fmt.Errorf("operator %s is not supported by field %s", op, field)
+	}
+
+	var pred node = predicateFunc(func(r Record) bool {
+		for _, v := range values(r) {
+			if match(v) {
+				return true
+			}
+		}
+		return false
+	})
+	if op == "!~" || op == "!=" {
+		pred = notNode{pred}
+	}
+	return pred, nil
+}
+
+func ipPredicate(op, value string) (node, error) {
+	switch op {
+	case "=~", "!~":
+		return stringPredicate("ip", op, value, func(r Record) []string { return []string{r.IP} })
+	case "in":
+		prefix, err := config.ParsePrefix(value)
+		if err != nil {
+			return nil, err
+		}
+		return predicateFunc(func(r Record) bool {
+			return config.PrefixContains(prefix, r.IP)
+		}), nil
+	case "==", "!=":
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP address %q: %w", value, err)
+		}
+		addr = addr.Unmap()
+
+		var pred node = predicateFunc(func(r Record) bool {
+			ip, err := netip.ParseAddr(r.IP)
+			return err == nil && ip.Unmap() == addr
+		})
+		if op == "!=" {
+			pred = notNode{pred}
+		}
+		return pred, nil
+	default:
+		return nil, fmt.Errorf("operator %s is not supported by field ip", op)
+	}
+}
+
+// timePredicate compares the time of a record, records without a timestamp never match.
+func timePredicate(op, value string) (node, error) {
+	t, err := config.ParseTime(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var compare func(c int) bool
+	switch op {
+	case "==":
+		compare = func(c int) bool { return c == 0 }
+	case "!=":
+		compare = func(c int) bool { return c != 0 }
+	case "<":
+		compare = func(c int) bool { return c < 0 }
+	case "<=":
+		compare = func(c int) bool { return c <= 0 }
+	case ">":
+		compare = func(c int) bool { return c > 0 }
+	case ">=":
+		compare = func(c int) bool { return c >= 0 }
+	default:
+		return nil, fmt.Errorf("operator %s is not supported by field time", op)
+	}
+
+	return predicateFunc(func(r Record) bool {
+		if r.Time.IsZero() {
+			return false
+		}
+		return compare(r.Time.Compare(t))
+	}), nil
+}
@@ -0,0 +1,90 @@
+package filter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExprMatches(t *testing.T) {
+	record := Record{
+		File:      "/logs/server.log",
+		Time:      time.Date(2024, 5, 2, 18, 30, 0, 0, time.Local),
+		IP:        "1.2.3.4",
+		Nicknames: []string{"player", "bot123"},
+		Text:      "join discord.gg/xyz",
+	}
+
+	tests := []struct {
+		expr     string
+		expected bool
+	}{
+		{`nick =~ "bot" && text =~ "discord" && ip in 1.2.0.0/16 && time > 2024-05-01`, true},
+		{`nick =~ "^bot$"`, false},
+		{`nick == player`, true},
+		{`nick != player`, false},
+		{`nick !~ "admin"`, true},
+		{`text =~ 'discord\.gg/\w+'`, true},
+		{`text == "join \"discord\""`, false},
+		{`ip == 1.2.3.4 && ip != 1.2.3.5`, true},
+		{`ip in 1.2.3.4`, true},
+		{`ip in 10.0.0.0/8`, false},
+		{`ip =~ "^1\.2\."`, true},
+		{`time >= '2024-05-02 18:30' && time < "2024-05-02 18:31"`, true},
+		{`time <= 2024-05-02`, false},
+		{`file =~ server`, true},
+		{`!(ip in 10.0.0.0/8) && (nick == admin || text =~ discord)`, true},
+		{`nick == admin || nick == root && text =~ discord`, false},
+		{`!nick == player`, false},
+	}
+
+	for _, test := range tests {
+		expr, err := Compile(test.expr)
+		if err != nil {
+			t.Fatalf("%s: %v", test.expr, err)
+		}
+		if actual := expr.Matches(record); actual != test.expected {
+			t.Errorf("%s: expected %t, got %t", test.expr, test.expected, actual)
+		}
+	}
+}
+
+func TestExprWithoutTime(t *testing.T) {
+	expr, err := Compile(`time < now || time >= now`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expr.Matches(Record{IP: "1.2.3.4"}) {
+		t.Error("records without time must not match time predicates")
+	}
+
+	var nilExpr *Expr
+	if !nilExpr.Matches(Record{}) {
+		t.Error("nil expression must match every record")
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	invalid := []string{
+		``,
+		`nick`,
+		`nick =~`,
+		`nick < bot`,
+		`ip in 1.2.3`,
+		`ip < 1.2.3.4`,
+		`time =~ 2024`,
+		`time > yesterday`,
+		`level == info`,
+		`(nick == bot`,
+		`nick == "bot`,
+		`nick =~ "("`,
+		`nick == bot text == x`,
+		`nick == bot &&`,
+	}
+
+	for _, expr := range invalid {
+		_, err := Compile(expr)
+		if err == nil {
+			t.Errorf("%s: expected an error", expr)
+		}
+	}
+}
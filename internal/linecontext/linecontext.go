@@ -0,0 +1,115 @@
+// Package linecontext attaches the surrounding log lines to search results like grep -A, -B and -C.
+package linecontext
+
+import (
+	"github.com/jxsl13/twlog/event"
+	"github.com/jxsl13/twlog/model"
+)
+
+// EmitFunc is called with search results and their context lines once all lines after the last result have been read.
+// Like grep, results whose windows overlap or are adjacent are emitted together and share the merged window.
+type EmitFunc[T any] func(items []T, lines model.ContextLines) error
+
+// Collector collects the context lines of the search results of a single log file.
+// All lines of the file must be passed to Line and Match must be called after the line of the match.
+type Collector[T any] struct {
+	before int
+	after  int
+	emit   EmitFunc[T]
+
+	// the most recent lines, including the current line
+	recent model.ContextLines
+	// results that are waiting for the lines after them, nil in case there are none
+	group *group[T]
+}
+
+// group contains search results whose windows are merged.
+type group[T any] struct {
+	items []T
+	last  int
+	lines model.ContextLines
+}
+
+// New creates a collector that keeps the before lines in front of and the after lines behind a match.
+func New[T any](before, after int, emit EmitFunc[T]) *Collector[T] {
+	return &Collector[T]{
+		before: before,
+		after:  after,
+		emit:   emit,
+		recent: make(model.ContextLines, 0, before+1),
+	}
+}
+
+// Lines passes the raw lines of the most recent call to the scanner's Scan method to the collector.
+func (c *Collector[T]) Lines(lines []event.RawLine) error {
+	for _, l := range lines {
+		err := c.Line(l.LineNumber, l.Line)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Line adds a line of the log file and emits the search results whose window cannot be extended by later matches anymore.
+func (c *Collector[T]) Line(lineNumber int, line string) error {
+	l := model.ContextLine{LineNumber: lineNumber, Line: line}
+
+	if c.group != nil && lineNumber <= c.group.last {
+		c.group.lines = append(c.group.lines, l)
+	}
+
+	if len(c.recent) > c.before {
+		c.recent = append(c.recent[:0], c.recent[1:]...)
+	}
+	c.recent = append(c.recent, l)
+
+	// the window of a match on the current line starts before lines earlier
+	if c.group != nil && c.group.last+1 < lineNumber-c.before {
+		return c.Close()
+	}
+	return nil
+}
+
+// Match adds a search result that was found on the given line, which must be the most recent line.
+func (c *Collector[T]) Match(lineNumber int, item T) error {
+	markMatch(c.recent, lineNumber)
+
+	window := make(model.ContextLines, 0, c.before+c.after+1)
+	for _, l := range c.recent {
+		if l.LineNumber >= lineNumber-c.before {
+			window = append(window, l)
+		}
+	}
+
+	if c.group == nil {
+		c.group = &group[T]{
+			items: make([]T, 0, 4),
+			lines: window,
+		}
+	} else {
+		// Line emitted all results whose window neither overlaps nor is adjacent
+		c.group.lines = c.group.lines.Merge(window)
+	}
+	c.group.items = append(c.group.items, item)
+	c.group.last = lineNumber + c.after
+	return nil
+}
+
+// Close emits all remaining search results, e.g. at the end of the log file.
+func (c *Collector[T]) Close() error {
+	if c.group == nil {
+		return nil
+	}
+	g := c.group
+	c.group = nil
+	return c.emit(g.items, g.lines)
+}
+
+func markMatch(lines model.ContextLines, lineNumber int) {
+	for i := range lines {
+		if lines[i].LineNumber == lineNumber {
+			lines[i].Match = true
+		}
+	}
+}
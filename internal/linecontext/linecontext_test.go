@@ -0,0 +1,67 @@
+package linecontext
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jxsl13/twlog/model"
+)
+
+func TestCollector(t *testing.T) {
+	tests := []struct {
+		name     string
+		before   int
+		after    int
+		lines    int
+		matches  []int
+		expected []string
+	}{
+		{"before", 2, 0, 5, []int{1, 5}, []string{"1=1:l1", "5=3-l3|4-l4|5:l5"}},
+		{"after", 0, 2, 6, []int{1, 5}, []string{"1=1:l1|2-l2|3-l3", "5=5:l5|6-l6"}},
+		{"overlapping", 1, 1, 5, []int{2, 3}, []string{"2,3=1-l1|2:l2|3:l3|4-l4"}},
+		{"adjacent", 1, 1, 7, []int{2, 5}, []string{"2,5=1-l1|2:l2|3-l3|4-l4|5:l5|6-l6"}},
+		{"separated", 1, 1, 8, []int{2, 6}, []string{"2=1-l1|2:l2|3-l3", "6=5-l5|6:l6|7-l7"}},
+		{"end of file", 1, 3, 3, []int{3}, []string{"3=2-l2|3:l3"}},
+	}
+
+	for _, test := range tests {
+		actual := make([]string, 0, len(test.matches))
+		c := New(test.before, test.after, func(items []int, lines model.ContextLines) error {
+			matches := make([]string, 0, len(items))
+			for _, item := range items {
+				matches = append(matches, strconv.Itoa(item))
+			}
+			window := make([]string, 0, len(lines))
+			for _, l := range lines {
+				window = append(window, l.String())
+			}
+			actual = append(actual, strings.Join(matches, ",")+"="+strings.Join(window, "|"))
+			return nil
+		})
+
+		for n := 1; n <= test.lines; n++ {
+			err := c.Line(n, fmt.Sprintf("l%d", n))
+			if err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+			for _, m := range test.matches {
+				if m == n {
+					err = c.Match(n, n)
+					if err != nil {
+						t.Fatalf("%s: %v", test.name, err)
+					}
+				}
+			}
+		}
+		err := c.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		if strings.Join(actual, ",") != strings.Join(test.expected, ",") {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, actual)
+		}
+	}
+}
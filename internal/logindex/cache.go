@@ -0,0 +1,192 @@
+package logindex
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"sync"
+
+	"github.com/jxsl13/twlog/event"
+	"github.com/jxsl13/twlog/fswalk"
+	"github.com/jxsl13/twlog/internal/diag"
+	"github.com/jxsl13/twlog/internal/dialect"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Builder updates the index while the log files and archives are walked.
+// Log files and archives that did not change since they were indexed are skipped.
+type Builder struct {
+	idx *Index
+
+	mu      sync.Mutex
+	visited map[string]bool
+	skipped int
+}
+
+// NewBuilder returns the cache that must be passed to the walk config in order to only walk changed files.
+func NewBuilder(idx *Index) *Builder {
+	return &Builder{
+		idx:     idx,
+		visited: make(map[string]bool, 64),
+	}
+}
+
+var _ fswalk.Cache = (*Builder)(nil)
+
+func (b *Builder) visit(path string, skipped bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.visited[path] = true
+	if skipped {
+		b.skipped++
+	}
+}
+
+// Cached skips the log files and archives that did not change since they were indexed.
+func (b *Builder) Cached(path string, info fs.FileInfo, _ func(filePath string, file io.Reader) error) ([]string, bool, error) {
+	var (
+		s   source
+		ok  bool
+		err error
+	)
+	err = b.idx.db.View(func(tx *bolt.Tx) error {
+		s, ok, err = b.idx.source(tx, path)
+		return err
+	})
+	if err != nil || !ok || !s.unchanged(info) {
+		return nil, false, err
+	}
+
+	b.visit(path, true)
+	return s.Files, true, nil
+}
+
+// Walked stores the modification time, size and log files of a log file or archive that has been indexed.
+func (b *Builder) Walked(path string, info fs.FileInfo, filePaths []string) error {
+	err := b.idx.putSource(path, info, filePaths)
+	if err != nil {
+		return fmt.Errorf("failed to index %s: %w", path, err)
+	}
+	b.visit(path, false)
+	return nil
+}
+
+// Index parses a log file and replaces its indexed events.
+func (b *Builder) Index(ctx context.Context, filePath string, file io.Reader) error {
+	err := b.idx.resetFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to index %s: %w", filePath, err)
+	}
+
+	var (
+		scanner = dialect.NewScanner(ctx, file)
+		batch   = make([]event.Event, 0, batchSize)
+		n       uint64
+	)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := b.idx.putBatch(filePath, n, batch)
+		if err != nil {
+			return fmt.Errorf("failed to index %s: %w", filePath, err)
+		}
+		n++
+		batch = batch[:0]
+		return nil
+	}
+
+	for scanner.Scan() {
+		batch = append(batch, scanner.Event())
+		if len(batch) == batchSize {
+			err = flush()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	diag.ParseFailures(ctx, filePath, scanner.Malformed())
+
+	err = scanner.Err()
+	if err != nil {
+		return err
+	}
+	return flush()
+}
+
+// Skipped returns the number of log files and archives that did not change since they were indexed.
+func (b *Builder) Skipped() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.skipped
+}
+
+// Prune removes all log files and archives from the index that were not walked.
+func (b *Builder) Prune() ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.idx.Prune(func(path string) bool {
+		return b.visited[path]
+	})
+}
+
+// Reader provides the indexed events of log files and archives to searches.
+// Log files and archives that are not indexed or changed since they were indexed are read instead.
+type Reader struct {
+	idx *Index
+}
+
+// NewReader returns the cache that must be passed to the walk config in order to search the index.
+func NewReader(idx *Index) *Reader {
+	return &Reader{idx: idx}
+}
+
+var _ fswalk.Cache = (*Reader)(nil)
+
+// Cached calls do with the indexed events of every log file of an unchanged log file or archive.
+func (r *Reader) Cached(path string, info fs.FileInfo, do func(filePath string, file io.Reader) error) ([]string, bool, error) {
+	var (
+		s  source
+		ok bool
+	)
+	err := r.idx.db.View(func(tx *bolt.Tx) error {
+		var err error
+		s, ok, err = r.idx.source(tx, path)
+		if err != nil || !ok || !s.unchanged(info) {
+			return err
+		}
+
+		// the events are only valid during the transaction
+		for _, filePath := range s.Files {
+			file, err := r.idx.file(tx, filePath)
+			if err != nil {
+				return err
+			}
+			err = do(filePath, file)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	switch {
+	case !ok:
+		slog.Warn("file is not indexed, reading it instead", "path", path)
+		return nil, false, nil
+	case !s.unchanged(info):
+		slog.Warn("file changed since it was indexed, reading it instead", "path", path)
+		return nil, false, nil
+	}
+	return s.Files, true, nil
+}
+
+// Walked does nothing, searches do not update the index.
+func (r *Reader) Walked(string, fs.FileInfo, []string) error {
+	return nil
+}
@@ -0,0 +1,268 @@
+// Package logindex stores the parsed events of log files and archives in an on-disk index,
+// which allows to search them again without reading, decompressing and parsing the files.
+// The events of every log file are stored in compressed batches, lines without event are not indexed.
+//
+// The index keeps the modification time and the size of every indexed log file and archive.
+// Files that changed are read again when the index is updated or searched.
+package logindex
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jxsl13/twlog/event"
+	bolt "go.etcd.io/bbolt"
+)
+
+// version of the index format, indexes of other versions must be rebuilt
+const version = "3"
+
+// batchSize is the number of events that are compressed and written together
+const batchSize = 1024
+
+var (
+	metaBucket    = []byte("meta")
+	sourcesBucket = []byte("sources")
+	filesBucket   = []byte("files")
+	versionKey    = []byte("version")
+)
+
+// Index is an on-disk index of log files.
+type Index struct {
+	db    *bolt.DB
+	once  sync.Once
+	close error
+}
+
+// Open opens the index at path. A read only index can be searched concurrently by several processes,
+// while updating an index requires exclusive access.
+func Open(path string, readOnly bool) (*Index, error) {
+	db, err := bolt.Open(path, 0o644, &bolt.Options{
+		Timeout:  time.Second,
+		ReadOnly: readOnly,
+	})
+	if err != nil {
+		if readOnly && errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("index %s does not exist, create it with index build", path)
+		}
+		if errors.Is(err, bolt.ErrTimeout) {
+			return nil, fmt.Errorf("index %s is locked by another process", path)
+		}
+		return nil, fmt.Errorf("failed to open index %s: %w", path, err)
+	}
+
+	if readOnly {
+		err = db.View(func(tx *bolt.Tx) error {
+			meta := tx.Bucket(metaBucket)
+			if meta == nil {
+				return fmt.Errorf("%s is not an index, create it with index build", path)
+			}
+			return checkVersion(meta.Get(versionKey))
+		})
+	} else {
+		err = db.Update(func(tx *bolt.Tx) error {
+			meta, err := tx.CreateBucketIfNotExists(metaBucket)
+			if err != nil {
+				return err
+			}
+			if v := meta.Get(versionKey); v != nil {
+				return checkVersion(v)
+			}
+			for _, name := range [][]byte{sourcesBucket, filesBucket} {
+				_, err = tx.CreateBucketIfNotExists(name)
+				if err != nil {
+					return err
+				}
+			}
+			return meta.Put(versionKey, []byte(version))
+		})
+	}
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Index{db: db}, nil
+}
+
+func checkVersion(v []byte) error {
+	if string(v) != version {
+		return fmt.Errorf("index version %q is not supported, rebuild the index with version %s", v, version)
+	}
+	return nil
+}
+
+// Close closes the index, it may be called several times.
+func (idx *Index) Close() error {
+	idx.once.Do(func() {
+		idx.close = idx.db.Close()
+	})
+	return idx.close
+}
+
+// source is a log file or archive in the index.
+type source struct {
+	ModTime time.Time `json:"mod_time"`
+	Size    int64     `json:"size"`
+	// Files are the paths of the log files of the source, which are the path itself or the archive entries.
+	Files []string `json:"files"`
+}
+
+func (s source) unchanged(info fs.FileInfo) bool {
+	return s.Size == info.Size() && s.ModTime.Equal(info.ModTime())
+}
+
+// source returns the indexed source at path.
+func (idx *Index) source(tx *bolt.Tx, path string) (source, bool, error) {
+	data := tx.Bucket(sourcesBucket).Get([]byte(path))
+	if data == nil {
+		return source{}, false, nil
+	}
+
+	var s source
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return source{}, false, fmt.Errorf("invalid index entry of %s: %w", path, err)
+	}
+	return s, true, nil
+}
+
+// Sources returns the paths of all indexed log files and archives.
+func (idx *Index) Sources() ([]string, error) {
+	paths := make([]string, 0, 64)
+	err := idx.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(sourcesBucket).ForEach(func(k, _ []byte) error {
+			paths = append(paths, string(k))
+			return nil
+		})
+	})
+	return paths, err
+}
+
+// resetFile removes the events of a log file before it is indexed again.
+func (idx *Index) resetFile(filePath string) error {
+	return idx.db.Update(func(tx *bolt.Tx) error {
+		files := tx.Bucket(filesBucket)
+		if files.Bucket([]byte(filePath)) != nil {
+			err := files.DeleteBucket([]byte(filePath))
+			if err != nil {
+				return err
+			}
+		}
+		_, err := files.CreateBucket([]byte(filePath))
+		return err
+	})
+}
+
+// putBatch appends the n-th batch of events of a log file.
+// Every batch is written in its own transaction, which keeps the memory usage of large files bounded.
+func (idx *Index) putBatch(filePath string, n uint64, events []event.Event) error {
+	data, err := encodeBatch(events)
+	if err != nil {
+		return err
+	}
+
+	return idx.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(filesBucket).Bucket([]byte(filePath))
+		if b == nil {
+			return fmt.Errorf("missing events of %s in index", filePath)
+		}
+		// keys are appended in order
+		b.FillPercent = 1
+		return b.Put(binary.BigEndian.AppendUint64(nil, n), data)
+	})
+}
+
+// file returns the replayer of the indexed events of a log file, which is valid until the transaction is closed.
+func (idx *Index) file(tx *bolt.Tx, filePath string) (*replayReader, error) {
+	b := tx.Bucket(filesBucket).Bucket([]byte(filePath))
+	if b == nil {
+		return nil, fmt.Errorf("missing events of %s in index", filePath)
+	}
+	return &replayReader{c: b.Cursor()}, nil
+}
+
+// putSource updates the modification time, size and log files of a source and
+// removes the events of log files that the source does not contain anymore.
+func (idx *Index) putSource(path string, info fs.FileInfo, filePaths []string) error {
+	return idx.db.Update(func(tx *bolt.Tx) error {
+		old, ok, err := idx.source(tx, path)
+		if err != nil {
+			return err
+		}
+		if ok {
+			err = deleteFiles(tx, old.Files, filePaths)
+			if err != nil {
+				return err
+			}
+		}
+
+		data, err := json.Marshal(source{
+			ModTime: info.ModTime(),
+			Size:    info.Size(),
+			Files:   filePaths,
+		})
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(sourcesBucket).Put([]byte(path), data)
+	})
+}
+
+// deleteFiles removes the events of all files that are not kept.
+func deleteFiles(tx *bolt.Tx, filePaths, keep []string) error {
+	files := tx.Bucket(filesBucket)
+	for _, filePath := range filePaths {
+		if slices.Contains(keep, filePath) || files.Bucket([]byte(filePath)) == nil {
+			continue
+		}
+		err := files.DeleteBucket([]byte(filePath))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Prune removes all sources and their events that are not kept.
+func (idx *Index) Prune(keep func(path string) bool) (removed []string, err error) {
+	removed = make([]string, 0, 4)
+	err = idx.db.Update(func(tx *bolt.Tx) error {
+		sources := tx.Bucket(sourcesBucket)
+
+		paths := make([]string, 0, 4)
+		err := sources.ForEach(func(k, _ []byte) error {
+			if !keep(string(k)) {
+				paths = append(paths, string(k))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, path := range paths {
+			s, _, err := idx.source(tx, path)
+			if err != nil {
+				return err
+			}
+			err = deleteFiles(tx, s.Files, nil)
+			if err != nil {
+				return err
+			}
+			err = sources.Delete([]byte(path))
+			if err != nil {
+				return err
+			}
+			removed = append(removed, path)
+		}
+		return nil
+	})
+	return removed, err
+}
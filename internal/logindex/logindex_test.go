@@ -0,0 +1,142 @@
+package logindex
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jxsl13/twlog/event"
+	"github.com/jxsl13/twlog/fswalk"
+)
+
+const testLog = "2024-05-01 18:01:12 I server: player has entered the game. ClientID=1 addr=<{1.2.3.4:41234}> sevendown=0\n" +
+	"2024-05-01 18:01:13 I chat: 1:-2:spam: free: skins\n" +
+	"2024-05-01 18:01:14 I game: kill killer='1:spam' victim='2:other' weapon=5 special=0\n" +
+	"2024-05-01 18:01:15 I server: client dropped. cid=1 addr=<{1.2.3.4:41234}> reason=''\n"
+
+func build(t *testing.T, idx *Index, path string) (indexed []string, b *Builder) {
+	t.Helper()
+	ctx := context.Background()
+	b = NewBuilder(idx)
+	err := fswalk.Walk(ctx, fswalk.WalkConfig{Inputs: []string{path}, Cache: b}, func(filePath string, file io.Reader) error {
+		indexed = append(indexed, filePath)
+		return b.Index(ctx, filePath, file)
+	})
+	if err != nil {
+		t.Fatalf("failed to build index: %v", err)
+	}
+	return indexed, b
+}
+
+func TestIndex(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "ddnet.log")
+	err := os.WriteFile(logFile, []byte(testLog), 0o644)
+	if err != nil {
+		t.Fatalf("failed to write log file: %v", err)
+	}
+
+	idx, err := Open(filepath.Join(dir, "twlog.index"), false)
+	if err != nil {
+		t.Fatalf("failed to open index: %v", err)
+	}
+	defer idx.Close()
+
+	indexed, b := build(t, idx, logFile)
+	if !slices.Equal(indexed, []string{logFile}) || b.Skipped() != 0 {
+		t.Fatalf("expected %s to be indexed, got %v", logFile, indexed)
+	}
+
+	indexed, b = build(t, idx, logFile)
+	if len(indexed) != 0 || b.Skipped() != 1 {
+		t.Fatalf("expected unchanged file to be skipped, got %v", indexed)
+	}
+
+	// the events of the index are replayed instead of parsing the lines again
+	replayed := replay(t, idx, logFile)
+
+	parsed := make([]event.Event, 0, 4)
+	scanner := event.NewScanner(mustOpen(t, logFile))
+	for scanner.Scan() {
+		parsed = append(parsed, scanner.Event())
+	}
+	if !reflect.DeepEqual(replayed, parsed) {
+		t.Fatalf("expected replayed events %v, got %v", parsed, replayed)
+	}
+
+	removed, err := b.Prune()
+	if err != nil || len(removed) != 0 {
+		t.Fatalf("expected nothing to be pruned, got %v: %v", removed, err)
+	}
+	removed, err = NewBuilder(idx).Prune()
+	if err != nil || !slices.Equal(removed, []string{logFile}) {
+		t.Fatalf("expected %s to be pruned, got %v: %v", logFile, removed, err)
+	}
+	sources, err := idx.Sources()
+	if err != nil || len(sources) != 0 {
+		t.Fatalf("expected empty index, got %v: %v", sources, err)
+	}
+}
+
+func TestIndexBatches(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "ddnet.log")
+	// more events than fit into two batches
+	err := os.WriteFile(logFile, []byte(strings.Repeat(testLog, batchSize)), 0o644)
+	if err != nil {
+		t.Fatalf("failed to write log file: %v", err)
+	}
+
+	idx, err := Open(filepath.Join(dir, "twlog.index"), false)
+	if err != nil {
+		t.Fatalf("failed to open index: %v", err)
+	}
+	defer idx.Close()
+	build(t, idx, logFile)
+
+	replayed := replay(t, idx, logFile)
+	if len(replayed) < 2*batchSize {
+		t.Fatalf("expected at least %d events, got %d", 2*batchSize, len(replayed))
+	}
+	for i := 1; i < len(replayed); i++ {
+		prev, cur := replayed[i-1].Metadata().LineNumber, replayed[i].Metadata().LineNumber
+		if cur <= prev {
+			t.Fatalf("expected events in the order of their lines, got line %d after %d", cur, prev)
+		}
+	}
+}
+
+// replay returns the indexed events of a log file.
+func replay(t *testing.T, idx *Index, logFile string) []event.Event {
+	t.Helper()
+	var events []event.Event
+	err := fswalk.Walk(context.Background(), fswalk.WalkConfig{Inputs: []string{logFile}, Cache: NewReader(idx)}, func(filePath string, file io.Reader) error {
+		if _, ok := file.(event.Replayer); !ok {
+			t.Errorf("expected indexed events of %s", filePath)
+		}
+		scanner := event.NewScanner(file)
+		for scanner.Scan() {
+			events = append(events, scanner.Event())
+		}
+		return scanner.Err()
+	})
+	if err != nil {
+		t.Fatalf("failed to search index: %v", err)
+	}
+	return events
+}
+
+func mustOpen(t *testing.T, path string) io.Reader {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", path, err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
@@ -0,0 +1,169 @@
+package logindex
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/jxsl13/twlog/event"
+	bolt "go.etcd.io/bbolt"
+)
+
+// record is an indexed event, Kind is the name of its type.
+type record struct {
+	Kind  string          `json:"k"`
+	Event json.RawMessage `json:"e"`
+}
+
+// decoders contains the decoder of every event type.
+var decoders = map[string]func(data []byte) (event.Event, error){
+	kind[event.JoinEvent]():          decode[event.JoinEvent],
+	kind[event.EnterEvent]():         decode[event.EnterEvent],
+	kind[event.TeamJoinEvent]():      decode[event.TeamJoinEvent],
+	kind[event.ClientVersionEvent](): decode[event.ClientVersionEvent],
+	kind[event.LeaveEvent]():         decode[event.LeaveEvent],
+	kind[event.ChatEvent]():          decode[event.ChatEvent],
+	kind[event.NameChangeEvent]():    decode[event.NameChangeEvent],
+	kind[event.MapChangeEvent]():     decode[event.MapChangeEvent],
+	kind[event.BanEvent]():           decode[event.BanEvent],
+	kind[event.UnbanEvent]():         decode[event.UnbanEvent],
+	kind[event.RconEvent]():          decode[event.RconEvent],
+	kind[event.VoteKickEvent]():      decode[event.VoteKickEvent],
+}
+
+func kind[E event.Event]() string {
+	return reflect.TypeFor[E]().Name()
+}
+
+func decode[E event.Event](data []byte) (event.Event, error) {
+	var e E
+	err := json.Unmarshal(data, &e)
+	if err != nil {
+		return nil, err
+	}
+
+	// timestamps are parsed in the local time zone, json only keeps their offset
+	if v := reflect.ValueOf(&e).Elem().FieldByName("Time"); v.IsValid() {
+		if t, ok := v.Interface().(time.Time); ok && !t.IsZero() {
+			v.Set(reflect.ValueOf(t.Local()))
+		}
+	}
+	return e, nil
+}
+
+// encodeBatch returns the gzip compressed records of the events, one json object per line.
+func encodeBatch(events []event.Event) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(zw)
+	for _, e := range events {
+		k := reflect.TypeOf(e).Name()
+		if _, ok := decoders[k]; !ok {
+			return nil, fmt.Errorf("event %T cannot be indexed", e)
+		}
+
+		data, err := json.Marshal(e)
+		if err != nil {
+			return nil, err
+		}
+		err = enc.Encode(record{Kind: k, Event: data})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := zw.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeBatch(data []byte) ([]event.Event, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid batch of events in index: %w", err)
+	}
+	defer zr.Close()
+
+	events := make([]event.Event, 0, batchSize)
+	dec := json.NewDecoder(zr)
+	for {
+		var r record
+		err = dec.Decode(&r)
+		if errors.Is(err, io.EOF) {
+			return events, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid event in index: %w", err)
+		}
+
+		decode, ok := decoders[r.Kind]
+		if !ok {
+			return nil, fmt.Errorf("unknown event kind %q in index", r.Kind)
+		}
+		e, err := decode(r.Event)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s in index: %w", r.Kind, err)
+		}
+		events = append(events, e)
+	}
+}
+
+// replayReader provides the indexed events of a log file to event scanners, one batch at a time.
+// Reading it returns the lines of the events that have not been replayed yet.
+type replayReader struct {
+	c       *bolt.Cursor
+	started bool
+	events  []event.Event
+	lines   []byte
+}
+
+var _ event.Replayer = (*replayReader)(nil)
+
+func (r *replayReader) Replay() (event.Event, bool, error) {
+	for len(r.events) == 0 {
+		var k, v []byte
+		if !r.started {
+			k, v = r.c.First()
+			r.started = true
+		} else {
+			k, v = r.c.Next()
+		}
+		if k == nil {
+			return nil, false, nil
+		}
+
+		events, err := decodeBatch(v)
+		if err != nil {
+			return nil, false, err
+		}
+		r.events = events
+	}
+
+	e := r.events[0]
+	r.events = r.events[1:]
+	return e, true, nil
+}
+
+func (r *replayReader) Read(p []byte) (int, error) {
+	for len(r.lines) == 0 {
+		e, ok, err := r.Replay()
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			return 0, io.EOF
+		}
+		r.lines = append([]byte(e.Metadata().Line), '\n')
+	}
+
+	n := copy(p, r.lines)
+	r.lines = r.lines[n:]
+	return n, nil
+}
@@ -0,0 +1,174 @@
+// Package matcher matches texts against one or more search patterns and reports which pattern matched.
+package matcher
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/cloudflare/ahocorasick"
+)
+
+// Matcher matches a text against a set of patterns.
+// Implementations are safe for concurrent use.
+type Matcher interface {
+	// Match returns the first pattern, in the order the patterns were passed, that matches s.
+	Match(s string) (pattern string, ok bool)
+}
+
+// New compiles all patterns into a single matcher.
+// Fixed strings are matched as literal substrings, otherwise the patterns are regular expressions.
+// Empty patterns are rejected, they would either match every text or, as fixed strings, none.
+func New(patterns []string, fixedStrings, ignoreCase bool) (Matcher, error) {
+	if slices.Contains(patterns, "") {
+		return nil, errors.New("empty search pattern")
+	}
+	patterns = dedup(patterns)
+
+	if fixedStrings {
+		if len(patterns) == 1 {
+			return newLiteralMatcher(patterns[0], ignoreCase), nil
+		}
+		return newAhoCorasickMatcher(patterns, ignoreCase), nil
+	}
+	return newRegexpMatcher(patterns, ignoreCase)
+}
+
+// dedup removes duplicate patterns and keeps the order of the remaining ones.
+func dedup(patterns []string) []string {
+	seen := make(map[string]bool, len(patterns))
+	result := make([]string, 0, len(patterns))
+	for _, p := range patterns {
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		result = append(result, p)
+	}
+	return result
+}
+
+// literalMatcher is the fast path for a single fixed string.
+type literalMatcher struct {
+	pattern    string
+	literal    string
+	ignoreCase bool
+}
+
+func newLiteralMatcher(pattern string, ignoreCase bool) *literalMatcher {
+	literal := pattern
+	if ignoreCase {
+		literal = strings.ToLower(pattern)
+	}
+	return &literalMatcher{
+		pattern:    pattern,
+		literal:    literal,
+		ignoreCase: ignoreCase,
+	}
+}
+
+func (m *literalMatcher) Match(s string) (string, bool) {
+	if m.ignoreCase {
+		s = strings.ToLower(s)
+	}
+	if !strings.Contains(s, m.literal) {
+		return "", false
+	}
+	return m.pattern, true
+}
+
+// ahoCorasickMatcher searches for many fixed strings in a single pass.
+type ahoCorasickMatcher struct {
+	patterns   []string
+	ignoreCase bool
+	automaton  *ahocorasick.Matcher
+}
+
+func newAhoCorasickMatcher(patterns []string, ignoreCase bool) *ahoCorasickMatcher {
+	dictionary := patterns
+	if ignoreCase {
+		dictionary = make([]string, 0, len(patterns))
+		for _, p := range patterns {
+			dictionary = append(dictionary, strings.ToLower(p))
+		}
+	}
+	return &ahoCorasickMatcher{
+		patterns:   patterns,
+		ignoreCase: ignoreCase,
+		automaton:  ahocorasick.NewStringMatcher(dictionary),
+	}
+}
+
+func (m *ahoCorasickMatcher) Match(s string) (string, bool) {
+	if m.ignoreCase {
+		s = strings.ToLower(s)
+	}
+
+	hits := m.automaton.MatchThreadSafe([]byte(s))
+	if len(hits) == 0 {
+		return "", false
+	}
+	return m.patterns[slices.Min(hits)], true
+}
+
+// regexpMatcher rejects texts with a single combined regular expression
+// and only determines the matching pattern for texts that match.
+type regexpMatcher struct {
+	patterns []string
+	combined *regexp.Regexp
+	regexps  []*regexp.Regexp
+}
+
+func newRegexpMatcher(patterns []string, ignoreCase bool) (*regexpMatcher, error) {
+	flags := ""
+	if ignoreCase {
+		flags = "(?i)"
+	}
+
+	regexps := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(flags + p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+		regexps = append(regexps, re)
+	}
+
+	m := &regexpMatcher{
+		patterns: patterns,
+		regexps:  regexps,
+	}
+	if len(regexps) == 1 {
+		m.combined = regexps[0]
+		return m, nil
+	}
+
+	alternatives := make([]string, 0, len(patterns))
+	for _, p := range patterns {
+		alternatives = append(alternatives, "(?:"+p+")")
+	}
+	combined, err := regexp.Compile(flags + strings.Join(alternatives, "|"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to combine patterns: %w", err)
+	}
+	m.combined = combined
+	return m, nil
+}
+
+func (m *regexpMatcher) Match(s string) (string, bool) {
+	if !m.combined.MatchString(s) {
+		return "", false
+	}
+	if len(m.regexps) == 1 {
+		return m.patterns[0], true
+	}
+
+	for i, re := range m.regexps {
+		if re.MatchString(s) {
+			return m.patterns[i], true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,53 @@
+package matcher
+
+import (
+	"testing"
+)
+
+func TestMatcher(t *testing.T) {
+	tests := []struct {
+		name         string
+		patterns     []string
+		fixedStrings bool
+		ignoreCase   bool
+		text         string
+		expected     string
+	}{
+		{"literal", []string{"bot.xyz"}, true, false, "visit bot.xyz now", "bot.xyz"},
+		{"literal no regex", []string{"bot.xyz"}, true, false, "visit botaxyz now", ""},
+		{"literal case", []string{"bot.xyz"}, true, false, "visit BOT.XYZ now", ""},
+		{"literal ignore case", []string{"bot.xyz"}, true, true, "visit BOT.XYZ now", "bot.xyz"},
+		{"aho-corasick first pattern wins", []string{"telegram", "t.me/"}, true, false, "t.me/x telegram", "telegram"},
+		{"aho-corasick ignore case", []string{"discord", "T.ME/"}, true, true, "join t.me/x", "T.ME/"},
+		{"aho-corasick no match", []string{"discord", "t.me/"}, true, false, "hello", ""},
+		{"regex", []string{`bot\.xyz`}, false, false, "bot.xyz", `bot\.xyz`},
+		{"regex ignore case", []string{`^free`}, false, true, "FREE skins", `^free`},
+		{"regexes", []string{`disc[o0]rd`, `te[il]egram`}, false, false, "join teiegram", `te[il]egram`},
+		{"regexes with groups", []string{`(a)(b)`, `(c)`}, false, false, "c", `(c)`},
+		{"regexes no match", []string{`discord`, `telegram`}, false, false, "hello", ""},
+	}
+
+	for _, test := range tests {
+		m, err := New(test.patterns, test.fixedStrings, test.ignoreCase)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		pattern, ok := m.Match(test.text)
+		if ok != (test.expected != "") || pattern != test.expected {
+			t.Errorf("%s: expected %q, got %q (%t)", test.name, test.expected, pattern, ok)
+		}
+	}
+
+	_, err := New([]string{"ok", "("}, false, false)
+	if err == nil {
+		t.Error("expected an error for an invalid regex")
+	}
+
+	for _, fixedStrings := range []bool{true, false} {
+		_, err = New([]string{"ok", ""}, fixedStrings, false)
+		if err == nil {
+			t.Errorf("expected an error for an empty pattern (fixed strings %t)", fixedStrings)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package playerstate
+
+import (
+	"io"
+	"sync"
+
+	"github.com/jxsl13/twlog/fswalk"
+)
+
+// Store provides the client id -> IP state of log files.
+// Files of the same group share their state, which carries the state over rotated log files.
+// The groups are assigned by fswalk.Walk, which processes the files of a group sequentially.
+type Store struct {
+	mu     sync.Mutex
+	groups map[string]map[int]string
+}
+
+// NewStore creates a store for the files of a single walk.
+func NewStore() *Store {
+	return &Store{
+		groups: make(map[string]map[int]string, 8),
+	}
+}
+
+// Players returns the client id -> IP map for a file that fswalk.Walk passed to its callback.
+// Files without group get a new map.
+func (s *Store) Players(file io.Reader) map[int]string {
+	key, ok := fswalk.GroupOf(file)
+	if !ok {
+		return make(map[int]string, 64)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	players, found := s.groups[key]
+	if !found {
+		players = make(map[int]string, 64)
+		s.groups[key] = players
+	}
+	return players
+}
@@ -0,0 +1,124 @@
+package sharedconfig
+
+import (
+	"fmt"
+	"net/netip"
+	"reflect"
+	"time"
+
+	"github.com/jxsl13/twlog/internal/banlist"
+	"github.com/spf13/cobra"
+)
+
+func isBanFormat(format string) bool {
+	return isOneOf(format, FormatDDNetBan, FormatNFTables, FormatIPSet, FormatFail2Ban)
+}
+
+func (cfg *FormatConfig) printBanList(cmd *cobra.Command, a any) error {
+	ips, err := collectIPs(a)
+	if err != nil {
+		return fmt.Errorf("output format %s is not supported by the %s command: %w", cfg.Output, cmd.Name(), err)
+	}
+
+	threshold := cfg.BanCollapseThreshold
+	if cfg.Output == FormatFail2Ban {
+		// fail2ban only bans single hosts
+		threshold = 0
+	}
+	entries := banlist.Collapse(ips, threshold, cfg.BanCollapseBitsV4, cfg.BanCollapseBitsV6)
+
+	w := cmd.OutOrStdout()
+	switch cfg.Output {
+	case FormatDDNetBan:
+		return banlist.WriteDDNet(w, entries, cfg.BanDuration, cfg.BanReason)
+	case FormatNFTables:
+		return banlist.WriteNFTables(w, entries, cfg.BanDuration, cfg.BanReason, cfg.BanSetName)
+	case FormatIPSet:
+		return banlist.WriteIPSet(w, entries, cfg.BanDuration, cfg.BanReason, cfg.BanSetName)
+	case FormatFail2Ban:
+		return banlist.WriteFail2Ban(w, entries, cfg.BanReason)
+	default:
+		// should never happen
+		return fmt.Errorf("unsupported ban list format: %s", cfg.Output)
+	}
+}
+
+// collectIPs extracts the ip field of every result in a slice of structs as well as the
+// most recent time of each IP address. Slices of strings, e.g. the results of --ips-only,
+// are IP addresses without time.
+// Results without a valid IP address are skipped.
+func collectIPs(a any) (map[netip.Addr]time.Time, error) {
+	v := reflect.ValueOf(a)
+	if v.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("result is not a list: %T", a)
+	}
+
+	t := v.Type().Elem()
+	if t.Kind() == reflect.String {
+		ips := make(map[netip.Addr]time.Time, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			addr, ok := parseAddr(v.Index(i).String())
+			if !ok {
+				continue
+			}
+			ips[addr] = time.Time{}
+		}
+		return ips, nil
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("result does not contain IP addresses: %T", a)
+	}
+
+	ipField := -1
+	timeFields := make([]int, 0, 2)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Type == reflect.TypeFor[time.Time]() {
+			timeFields = append(timeFields, i)
+		} else if field.Type.Kind() == reflect.String && isIPField(field) {
+			ipField = i
+		}
+	}
+	if ipField < 0 {
+		return nil, fmt.Errorf("result does not contain IP addresses: %T", a)
+	}
+
+	ips := make(map[netip.Addr]time.Time, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		elem := v.Index(i)
+		addr, ok := parseAddr(elem.Field(ipField).String())
+		if !ok {
+			continue
+		}
+
+		latest := ips[addr]
+		for _, idx := range timeFields {
+			if t := elem.Field(idx).Interface().(time.Time); t.After(latest) {
+				latest = t
+			}
+		}
+		ips[addr] = latest
+	}
+	return ips, nil
+}
+
+// parseAddr accepts IP addresses with and without port.
+func parseAddr(s string) (netip.Addr, bool) {
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		addrPort, err := netip.ParseAddrPort(s)
+		if err != nil {
+			return netip.Addr{}, false
+		}
+		addr = addrPort.Addr()
+	}
+	return addr.Unmap().WithZone(""), true
+}
+
+func isIPField(field reflect.StructField) bool {
+	name, ok := jsonName(field)
+	return ok && name == "ip"
+}
@@ -0,0 +1,205 @@
+package sharedconfig
+
+import (
+	"encoding/csv"
+	"fmt"
+	"maps"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// printCSV prints a slice of results with a header row that is derived from the json tags of the result type.
+func printCSV(cmd *cobra.Command, a any, comma rune) error {
+	v := reflect.ValueOf(a)
+	if v.Kind() != reflect.Slice {
+		return fmt.Errorf("output format %s is not supported by the %s command", csvFormatName(comma), cmd.Name())
+	}
+
+	w := csv.NewWriter(cmd.OutOrStdout())
+	w.Comma = comma
+
+	first := reflect.Zero(v.Type().Elem())
+	if v.Len() > 0 {
+		first = v.Index(0)
+	}
+	omit := emptyColumns(first)
+
+	err := w.Write(csvHeader(v.Type().Elem(), omit))
+	if err != nil {
+		return fmt.Errorf("failed to write csv header: %w", err)
+	}
+
+	for i := 0; i < v.Len(); i++ {
+		err = w.Write(csvRecord(v.Index(i), omit))
+		if err != nil {
+			return fmt.Errorf("failed to write csv record: %w", err)
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+// printCSVItem prints a single result and the header row before the first result.
+func (cfg *FormatConfig) printCSVItem(cmd *cobra.Command, a any, comma rune) error {
+	v := reflect.ValueOf(a)
+
+	w := csv.NewWriter(cmd.OutOrStdout())
+	w.Comma = comma
+
+	if !cfg.headerPrinted {
+		cfg.omittedColumns = emptyColumns(v)
+		err := w.Write(csvHeader(v.Type(), cfg.omittedColumns))
+		if err != nil {
+			return fmt.Errorf("failed to write csv header: %w", err)
+		}
+		cfg.headerPrinted = true
+	}
+
+	err := w.Write(csvRecord(v, cfg.omittedColumns))
+	if err != nil {
+		return fmt.Errorf("failed to write csv record: %w", err)
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+func csvFormatName(comma rune) string {
+	if comma == '\t' {
+		return FormatTSV
+	}
+	return FormatCSV
+}
+
+// csvHeader returns the column names of all fields of a struct type that are not omitted.
+// Non-struct types have a single column called value.
+func csvHeader(t reflect.Type, omit map[string]bool) []string {
+	if t.Kind() != reflect.Struct || t == reflect.TypeFor[time.Time]() {
+		return []string{"value"}
+	}
+
+	header := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			header = append(header, csvHeader(field.Type, omit)...)
+			continue
+		}
+
+		name, ok := csvName(field)
+		if !ok || omit[name] {
+			continue
+		}
+		header = append(header, name)
+	}
+	return header
+}
+
+// csvRecord returns the values of all fields that csvHeader returns names for.
+func csvRecord(v reflect.Value, omit map[string]bool) []string {
+	t := v.Type()
+	if t.Kind() != reflect.Struct || t == reflect.TypeFor[time.Time]() {
+		return []string{csvValue(v)}
+	}
+
+	record := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			record = append(record, csvRecord(v.Field(i), omit)...)
+			continue
+		}
+
+		if name, ok := csvName(field); !ok || omit[name] {
+			continue
+		}
+		record = append(record, csvValue(v.Field(i)))
+	}
+	return record
+}
+
+func csvValue(v reflect.Value) string {
+	switch x := v.Interface().(type) {
+	case time.Time:
+		if x.IsZero() {
+			return ""
+		}
+		return x.Format(time.DateTime)
+	case fmt.Stringer:
+		return x.String()
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		values := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			values = append(values, csvValue(v.Index(i)))
+		}
+		return strings.Join(values, ",")
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+// emptyColumns returns the columns of the fields with the tag csv:",omitempty" that are empty in v.
+// All records of a table have the same columns, which is why they are derived from the first record,
+// e.g. the matched pattern is only set in case several patterns were searched.
+func emptyColumns(v reflect.Value) map[string]bool {
+	t := v.Type()
+	if t.Kind() != reflect.Struct || t == reflect.TypeFor[time.Time]() {
+		return nil
+	}
+
+	omit := make(map[string]bool, 1)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			maps.Copy(omit, emptyColumns(v.Field(i)))
+			continue
+		}
+
+		_, opts, _ := strings.Cut(field.Tag.Get("csv"), ",")
+		if opts != "omitempty" || !v.Field(i).IsZero() {
+			continue
+		}
+		if name, ok := csvName(field); ok {
+			omit[name] = true
+		}
+	}
+	return omit
+}
+
+// csvName returns the column name of a field, which is its json name.
+// Fields with the tag csv:"-" are not printed, e.g. nested lists.
+func csvName(field reflect.StructField) (string, bool) {
+	if field.Tag.Get("csv") == "-" {
+		return "", false
+	}
+	return jsonName(field)
+}
+
+// jsonName returns the name of a field in its json representation.
+func jsonName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, true
+}
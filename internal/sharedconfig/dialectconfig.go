@@ -0,0 +1,53 @@
+package sharedconfig
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jxsl13/twlog/internal/dialect"
+)
+
+// DialectConfig selects the log dialect of server forks whose log lines differ from the built-in ones.
+type DialectConfig struct {
+	File        string             `koanf:"dialect.file" description:"YAML or TOML file that defines log dialects with named capture groups for join, leave, chat and other lines, see the README"`
+	Name        string             `koanf:"dialect.name" description:"dialect that is used for all files, 'auto' detects the dialect of each file based on its first lines and in follow mode the dialect of each line, 'builtin' only uses the built-in dialect"`
+	DetectLines int                `koanf:"dialect.detect.lines" description:"number of lines at the beginning of each file that the dialect auto-detection looks at"`
+	Selection   *dialect.Selection `koanf:"-"`
+}
+
+func NewDialectConfig() DialectConfig {
+	return DialectConfig{
+		Name:        dialect.Auto,
+		DetectLines: 100,
+	}
+}
+
+func (cfg *DialectConfig) Validate() error {
+	if cfg.DetectLines <= 0 {
+		return errors.New("dialect detect lines must be greater than 0")
+	}
+
+	registry := dialect.NewRegistry()
+	if cfg.File != "" {
+		err := registry.Load(cfg.File)
+		if err != nil {
+			return err
+		}
+	}
+
+	names := registry.Names()
+	if !strings.EqualFold(cfg.Name, dialect.Auto) && !slices.ContainsFunc(names, func(name string) bool {
+		return strings.EqualFold(name, cfg.Name)
+	}) {
+		return fmt.Errorf("unknown dialect %q: must be %s or one of %v", cfg.Name, dialect.Auto, names)
+	}
+
+	cfg.Selection = &dialect.Selection{
+		Registry:    registry,
+		Name:        cfg.Name,
+		DetectLines: cfg.DetectLines,
+	}
+	return nil
+}
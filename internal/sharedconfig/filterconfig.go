@@ -0,0 +1,28 @@
+package sharedconfig
+
+import (
+	"github.com/jxsl13/twlog/internal/filter"
+)
+
+// FilterConfig contains the filter expression that is applied by every search command.
+type FilterConfig struct {
+	Where     string       `koanf:"where" short:"W" description:"filter expression that is evaluated against every event, e.g. 'nick =~ \"bot\" && text =~ \"discord\" && ip in 1.2.0.0/16 && time > 2024-05-01', fields: nick, text, ip, time, file"`
+	WhereExpr *filter.Expr `koanf:"-"`
+}
+
+func NewFilterConfig() FilterConfig {
+	return FilterConfig{}
+}
+
+func (cfg *FilterConfig) Validate() error {
+	if cfg.Where == "" {
+		return nil
+	}
+
+	expr, err := filter.Compile(cfg.Where)
+	if err != nil {
+		return err
+	}
+	cfg.WhereExpr = expr
+	return nil
+}
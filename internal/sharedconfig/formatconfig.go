@@ -2,8 +2,12 @@ package sharedconfig
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"regexp"
 	"strings"
+	"text/template"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -11,28 +15,113 @@ import (
 const (
 	FormatJSON = "json"
 	FormatText = "text"
+	FormatDOT  = "dot"
+	FormatCSV  = "csv"
+	FormatTSV  = "tsv"
+
+	FormatTemplate = "template"
+
+	// ban list formats
+	FormatDDNetBan = "ddnet-ban"
+	FormatNFTables = "nftables"
+	FormatIPSet    = "ipset"
+	FormatFail2Ban = "fail2ban"
 )
 
+// nftables and ipset identifiers
+var setNameRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_-]{0,24}$`)
+
+// DOTer is implemented by results that can be rendered as Graphviz DOT graph.
+type DOTer interface {
+	DOT() string
+}
+
 type FormatConfig struct {
-	Output string `koanf:"output" short:"o" description:"output format, one of 'json' or 'text'"`
+	Output       string `koanf:"output" short:"o" description:"output format, one of 'json', 'text', 'csv', 'tsv', 'template', 'dot' (graphs only) or one of the ban list formats 'ddnet-ban', 'nftables', 'ipset' and 'fail2ban'"`
+	Template     string `koanf:"template" description:"go text/template that is executed for every result in case of the template output format, e.g. '{{.IP}} # {{.Nickname}}'"`
+	TemplateFile string `koanf:"template.file" description:"file that contains the go text/template for the template output format"`
+	Stream       bool   `koanf:"stream" short:"S" description:"print results as soon as each file has been processed, json is printed as newline delimited json (supported by the said commands)"`
+	Ordered      bool   `koanf:"ordered" description:"print streamed results in the order of the searched files instead of the order in which they finish"`
+
+	BanDuration          time.Duration `koanf:"ban.duration" description:"ban duration of the ban list formats, 0 bans permanently"`
+	BanReason            string        `koanf:"ban.reason" description:"ban reason of the ban list formats"`
+	BanSetName           string        `koanf:"ban.set.name" description:"name of the nftables table and sets or the ipset sets"`
+	BanCollapseThreshold int           `koanf:"ban.collapse.threshold" description:"ban whole networks instead of single IPs as soon as this many IPs share a network, 0 disables collapsing"`
+	BanCollapseBitsV4    int           `koanf:"ban.collapse.bits.v4" description:"prefix length of IPv4 networks that are collapsed"`
+	BanCollapseBitsV6    int           `koanf:"ban.collapse.bits.v6" description:"prefix length of IPv6 networks that are collapsed"`
+
+	tmpl *template.Template
+	// whether the csv header of a stream has already been printed
+	headerPrinted bool
+	// columns of a stream that are left out, see emptyColumns
+	omittedColumns map[string]bool
 }
 
 func NewFormatConfig() FormatConfig {
 	return FormatConfig{
-		Output: FormatText,
+		Output:            FormatText,
+		BanDuration:       24 * time.Hour,
+		BanReason:         "banned by twlog",
+		BanSetName:        "twlog",
+		BanCollapseBitsV4: 24,
+		BanCollapseBitsV6: 64,
 	}
 }
 
 func (cfg *FormatConfig) Validate() error {
-	allowed := []string{FormatJSON, FormatText}
+	allowed := []string{
+		FormatJSON, FormatText, FormatCSV, FormatTSV, FormatTemplate, FormatDOT,
+		FormatDDNetBan, FormatNFTables, FormatIPSet, FormatFail2Ban,
+	}
 	lOutput := strings.ToLower(cfg.Output)
 	if !isOneOf(lOutput, allowed...) {
 		return fmt.Errorf("invalid output format %q: must be one of %v", cfg.Output, allowed)
 	}
 	cfg.Output = lOutput
+
+	if cfg.Output == FormatTemplate {
+		if (cfg.Template == "") == (cfg.TemplateFile == "") {
+			return errors.New("the template output format requires either a template or a template file")
+		}
+		tmpl, err := parseTemplate(cfg.Template, cfg.TemplateFile)
+		if err != nil {
+			return err
+		}
+		cfg.tmpl = tmpl
+	} else if cfg.Template != "" || cfg.TemplateFile != "" {
+		return fmt.Errorf("templates require the %s output format", FormatTemplate)
+	}
+
+	if cfg.Ordered && !cfg.Stream {
+		return errors.New("ordered output requires streaming to be enabled")
+	}
+	if cfg.Stream && !cfg.Streamable() {
+		return fmt.Errorf("output format %s cannot be streamed", cfg.Output)
+	}
+
+	if cfg.BanDuration < 0 {
+		return fmt.Errorf("invalid ban duration %s: must not be negative", cfg.BanDuration)
+	}
+	if !setNameRegex.MatchString(cfg.BanSetName) {
+		return fmt.Errorf("invalid ban set name %q: must match %s", cfg.BanSetName, setNameRegex)
+	}
+	if cfg.BanCollapseThreshold < 0 {
+		return fmt.Errorf("invalid ban collapse threshold %d: must not be negative", cfg.BanCollapseThreshold)
+	}
+	if cfg.BanCollapseBitsV4 < 0 || cfg.BanCollapseBitsV4 > 32 {
+		return fmt.Errorf("invalid IPv4 collapse prefix length %d: must be between 0 and 32", cfg.BanCollapseBitsV4)
+	}
+	if cfg.BanCollapseBitsV6 < 0 || cfg.BanCollapseBitsV6 > 128 {
+		return fmt.Errorf("invalid IPv6 collapse prefix length %d: must be between 0 and 128", cfg.BanCollapseBitsV6)
+	}
 	return nil
 }
 
+// Streamable returns true in case results can be printed one by one with PrintItem.
+func (cfg *FormatConfig) Streamable() bool {
+	return cfg.Output != FormatDOT && !isBanFormat(cfg.Output)
+}
+
 func isOneOf(s string, values ...string) bool {
 	for _, v := range values {
 		if s == v {
@@ -48,6 +137,16 @@ func (cfg *FormatConfig) Print(cmd *cobra.Command, a any) error {
 		return printText(cmd, a)
 	case FormatJSON:
 		return printJSON(cmd, a)
+	case FormatCSV:
+		return printCSV(cmd, a, ',')
+	case FormatTSV:
+		return printCSV(cmd, a, '\t')
+	case FormatTemplate:
+		return printTemplate(cmd, cfg.tmpl, a)
+	case FormatDOT:
+		return printDOT(cmd, a)
+	case FormatDDNetBan, FormatNFTables, FormatIPSet, FormatFail2Ban:
+		return cfg.printBanList(cmd, a)
 	default:
 		// should never happen
 		return fmt.Errorf("unsupported output format: %s", cfg.Output)
@@ -55,7 +154,10 @@ func (cfg *FormatConfig) Print(cmd *cobra.Command, a any) error {
 }
 
 func printText(cmd *cobra.Command, a any) error {
-	s := a.(fmt.Stringer) // will panic if used incorrectly
+	s, ok := a.(fmt.Stringer)
+	if !ok {
+		return fmt.Errorf("output format %s is not supported by the %s command", FormatText, cmd.Name())
+	}
 	_, err := fmt.Fprintln(cmd.OutOrStdout(), s.String())
 	return err
 }
@@ -73,3 +175,58 @@ func printJSON(cmd *cobra.Command, a any) error {
 	fmt.Fprint(cmd.OutOrStdout(), "\n")
 	return nil
 }
+
+func printDOT(cmd *cobra.Command, a any) error {
+	d, ok := a.(DOTer)
+	if !ok {
+		return fmt.Errorf("output format %s is not supported by the %s command", FormatDOT, cmd.Name())
+	}
+	_, err := fmt.Fprint(cmd.OutOrStdout(), d.DOT())
+	return err
+}
+
+// PrintItem prints a single result of a stream on its own line.
+// JSON results are printed as newline delimited JSON.
+func (cfg *FormatConfig) PrintItem(cmd *cobra.Command, a any) error {
+	switch cfg.Output {
+	case FormatText:
+		return printTextItem(cmd, a)
+	case FormatJSON:
+		return printJSONItem(cmd, a)
+	case FormatCSV:
+		return cfg.printCSVItem(cmd, a, ',')
+	case FormatTSV:
+		return cfg.printCSVItem(cmd, a, '\t')
+	case FormatTemplate:
+		return executeTemplate(cmd.OutOrStdout(), cfg.tmpl, a)
+	default:
+		return fmt.Errorf("output format %s cannot be streamed", cfg.Output)
+	}
+}
+
+func printTextItem(cmd *cobra.Command, a any) error {
+	var err error
+	switch v := a.(type) {
+	case string:
+		_, err = fmt.Fprintln(cmd.OutOrStdout(), v)
+	case fmt.Stringer:
+		_, err = fmt.Fprintln(cmd.OutOrStdout(), v.String())
+	default:
+		return fmt.Errorf("unsupported text result type: %T", a)
+	}
+	return err
+}
+
+func printJSONItem(cmd *cobra.Command, a any) error {
+	data, err := json.Marshal(a)
+	if err != nil {
+		return fmt.Errorf("failed to marshal json result: %w", err)
+	}
+	data = append(data, '\n')
+
+	_, err = cmd.OutOrStdout().Write(data)
+	if err != nil {
+		return fmt.Errorf("failed to print json result: %w", err)
+	}
+	return nil
+}
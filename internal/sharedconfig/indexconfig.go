@@ -0,0 +1,23 @@
+package sharedconfig
+
+import (
+	"errors"
+)
+
+// IndexConfig locates the on-disk index of the index commands.
+type IndexConfig struct {
+	File string `koanf:"index.file" description:"index file that is created by index build and searched by index query"`
+}
+
+func NewIndexConfig() IndexConfig {
+	return IndexConfig{
+		File: "twlog.index",
+	}
+}
+
+func (cfg *IndexConfig) Validate() error {
+	if cfg.File == "" {
+		return errors.New("index file is required")
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package sharedconfig
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// LogConfig configures the diagnostics that are logged to stderr.
+type LogConfig struct {
+	Level  string `koanf:"log.level" description:"minimum level of diagnostics that are logged to stderr, one of 'debug', 'info', 'warn' or 'error'"`
+	Format string `koanf:"log.format" description:"format of the diagnostics, one of 'text' or 'json'"`
+
+	level slog.Level
+}
+
+func NewLogConfig() LogConfig {
+	return LogConfig{
+		Level:  "info",
+		Format: LogFormatText,
+	}
+}
+
+func (cfg *LogConfig) Validate() error {
+	err := cfg.level.UnmarshalText([]byte(cfg.Level))
+	if err != nil {
+		return fmt.Errorf("invalid log level %q: must be one of debug, info, warn or error", cfg.Level)
+	}
+
+	allowed := []string{LogFormatText, LogFormatJSON}
+	lFormat := strings.ToLower(cfg.Format)
+	if !isOneOf(lFormat, allowed...) {
+		return fmt.Errorf("invalid log format %q: must be one of %v", cfg.Format, allowed)
+	}
+	cfg.Format = lFormat
+	return nil
+}
+
+// NewLogger creates a logger that writes the diagnostics to w.
+func (cfg *LogConfig) NewLogger(w io.Writer) *slog.Logger {
+	opts := &slog.HandlerOptions{
+		Level: cfg.level,
+	}
+
+	if cfg.Format == LogFormatJSON {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
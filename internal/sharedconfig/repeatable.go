@@ -0,0 +1,89 @@
+package sharedconfig
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// repeatableValue is a string flag value that can be set multiple times.
+// The config only sees a single string that contains all values joined by join.
+type repeatableValue struct {
+	values  []string
+	changed bool
+	join    func(values []string) string
+}
+
+func (v *repeatableValue) Set(s string) error {
+	if !v.changed {
+		// replace the default value
+		v.values = v.values[:0]
+		v.changed = true
+	}
+
+	// flags are parsed twice, by cobra and by the config parser
+	if !slices.Contains(v.values, s) {
+		v.values = append(v.values, s)
+	}
+	return nil
+}
+
+func (v *repeatableValue) String() string {
+	return v.join(v.values)
+}
+
+func (v *repeatableValue) Type() string {
+	return "string"
+}
+
+// repeatable replaces the value of a registered string flag in order to allow passing the flag multiple times.
+func repeatable(fs *pflag.FlagSet, name string, join func(values []string) string) {
+	f := fs.Lookup(name)
+	if f == nil {
+		return
+	}
+	f.Value = &repeatableValue{
+		values: splitNonEmpty(f.DefValue),
+		join:   join,
+	}
+}
+
+// joinList joins the values to a comma separated list.
+func joinList(values []string) string {
+	return strings.Join(values, ",")
+}
+
+// joinRegex joins the values to a single regex that matches if any of the values matches.
+func joinRegex(values []string) string {
+	if len(values) == 1 {
+		return values[0]
+	}
+
+	alternatives := make([]string, 0, len(values))
+	for _, v := range values {
+		alternatives = append(alternatives, "(?:"+v+")")
+	}
+	return strings.Join(alternatives, "|")
+}
+
+func splitNonEmpty(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return []string{s}
+}
+
+// flagAliases maps former flag names to their current names.
+var flagAliases = map[string]string{
+	// the koanf key follow.symlinks collides with the follow flag
+	"follow-symlinks": "dereference",
+}
+
+// NormalizeFlagName resolves the aliases of renamed flags, see cobra's SetGlobalNormalizationFunc.
+func NormalizeFlagName(_ *pflag.FlagSet, name string) pflag.NormalizedName {
+	if alias, ok := flagAliases[name]; ok {
+		name = alias
+	}
+	return pflag.NormalizedName(name)
+}
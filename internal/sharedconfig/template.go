@@ -0,0 +1,107 @@
+package sharedconfig
+
+import (
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// templateFuncs are available in user provided output templates.
+var templateFuncs = template.FuncMap{
+	"join":  templateJoin,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"quote": strconv.Quote,
+	"replace": func(old, new, s string) string {
+		return strings.ReplaceAll(s, old, new)
+	},
+	"cidr": templateCIDR,
+	"formatTime": func(layout string, t time.Time) string {
+		return t.Format(layout)
+	},
+	"unix": func(t time.Time) int64 {
+		return t.Unix()
+	},
+}
+
+// parseTemplate parses either the inline template or the template file.
+func parseTemplate(text, file string) (*template.Template, error) {
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template file: %w", err)
+		}
+		text = string(data)
+	}
+
+	tmpl, err := template.New("output").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// printTemplate executes the template for every element of a slice, or once for any other value.
+// Every execution is terminated by a line break unless the template already ends with one.
+func printTemplate(cmd *cobra.Command, tmpl *template.Template, a any) error {
+	v := reflect.ValueOf(a)
+	if v.Kind() != reflect.Slice {
+		return executeTemplate(cmd.OutOrStdout(), tmpl, a)
+	}
+
+	for i := 0; i < v.Len(); i++ {
+		err := executeTemplate(cmd.OutOrStdout(), tmpl, v.Index(i).Interface())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func executeTemplate(w io.Writer, tmpl *template.Template, a any) error {
+	var sb strings.Builder
+	err := tmpl.Execute(&sb, a)
+	if err != nil {
+		return fmt.Errorf("failed to execute template: %w", err)
+	}
+	if !strings.HasSuffix(sb.String(), "\n") {
+		sb.WriteByte('\n')
+	}
+	_, err = io.WriteString(w, sb.String())
+	return err
+}
+
+// templateJoin joins the elements of a slice, e.g. {{ .Nicknames | join ", " }}
+func templateJoin(sep string, a any) (string, error) {
+	v := reflect.ValueOf(a)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return "", fmt.Errorf("join expects a list, got %T", a)
+	}
+
+	values := make([]string, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		values = append(values, fmt.Sprint(v.Index(i).Interface()))
+	}
+	return strings.Join(values, sep), nil
+}
+
+// templateCIDR returns the network of the IP address with the given prefix length, e.g. {{ .IP | cidr 24 }}
+func templateCIDR(bits int, ip string) (string, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return "", err
+	}
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return "", err
+	}
+	return prefix.String(), nil
+}
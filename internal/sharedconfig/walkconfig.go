@@ -3,53 +3,103 @@ package sharedconfig
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"regexp"
 	"runtime"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/jxsl13/twlog/fswalk"
+	"github.com/spf13/pflag"
 )
 
 type WalkConfig struct {
-	SearchDir       string         `koanf:"search.dir" short:"d" description:"directory to search for files recursively"`
+	SearchDir       string         `koanf:"search.dir" short:"d" description:"directory to search for files recursively, can be passed multiple times or as comma separated list, defaults to the current directory unless inputs are passed, stdin is only read with --input -"`
+	SearchDirs      []string       `koanf:"-"`
+	Input           string         `koanf:"input" short:"I" description:"log file, archive or directory to search, - reads from stdin, can be passed multiple times or as comma separated list"`
+	Inputs          []string       `koanf:"-"`
+	ExcludeRegex    string         `koanf:"exclude.regex" short:"x" description:"regex to match directories and files in the search dirs that are skipped, matched against the path relative to the search dir with a leading slash, e.g. /backup/server.log, can be passed multiple times"`
+	ExcludeRegexp   *regexp.Regexp `koanf:"-"`
+	MaxDepth        int            `koanf:"max.depth" description:"maximum directory depth below the search dirs, 1 only searches the files directly in the search dirs, 0 is unlimited"`
+	FollowSymlinks  bool           `koanf:"dereference" short:"L" description:"follow symbolic links to files and directories in the search dirs"`
 	FileRegex       string         `koanf:"file.regex" short:"f" description:"regex to match files in the search dir"`
 	FileRegexp      *regexp.Regexp `koanf:"-"`
 	ArchiveRegex    string         `koanf:"archive.regex" short:"a" description:"regex to match archive files in the search dir"`
 	ArchiveRegexp   *regexp.Regexp `koanf:"-"`
 	IncludeArchives bool           `koanf:"include.archive" short:"A" description:"search inside archive files"`
+	ServerRegex     string         `koanf:"server.regex" description:"regex that groups the log files of one server by its first capture group, e.g. '/(srv[0-9]+)/', the files of a server are searched in chronological order and connected players are carried over rotated log files"`
+	ServerRegexp    *regexp.Regexp `koanf:"-"`
 	Concurrency     int            `koanf:"concurrency" short:"t" description:"number of concurrent workers to use"`
+	Follow          bool           `koanf:"follow" short:"F" description:"keep log files open like tail -F and print new results as they appear, handles rotated and truncated files as well as new files"`
+	FollowInterval  time.Duration  `koanf:"poll.interval" description:"interval in which followed files are checked for new data and the search dirs for new files"`
+
+	Stdin io.Reader `koanf:"-"`
+	// Cache is set by commands that search an index instead of the log files
+	Cache fswalk.Cache `koanf:"-"`
 }
 
 func NewWalkConfig() WalkConfig {
 	return WalkConfig{
-		SearchDir:    ".",
-		FileRegex:    `.*\.log$`,
-		ArchiveRegex: `\.(7z|bz2|gz|tar|xz|zip|xz|zst|lz)$`,
-		Concurrency:  max(1, runtime.NumCPU()),
+		FileRegex:      `.*\.log$`,
+		ArchiveRegex:   `\.(7z|bz2|gz|tar|xz|zip|xz|zst|lz)$`,
+		Concurrency:    max(1, runtime.NumCPU()),
+		FollowInterval: fswalk.DefaultPollInterval,
 	}
 }
 
 func (cfg *WalkConfig) ToFSWalkConfig() fswalk.WalkConfig {
 	return fswalk.WalkConfig{
-		SearchDir:       cfg.SearchDir,
+		SearchDirs:      cfg.SearchDirs,
+		Inputs:          cfg.Inputs,
+		Stdin:           cfg.Stdin,
 		FileRegexp:      cfg.FileRegexp,
+		ExcludeRegexp:   cfg.ExcludeRegexp,
+		MaxDepth:        cfg.MaxDepth,
+		FollowSymlinks:  cfg.FollowSymlinks,
+		GroupRegexp:     cfg.ServerRegexp,
+		PollInterval:    cfg.FollowInterval,
 		ArchiveRegexp:   cfg.ArchiveRegexp,
 		IncludeArchives: cfg.IncludeArchives,
 		Concurrency:     cfg.Concurrency,
+		Cache:           cfg.Cache,
 	}
 }
 
 func (cfg *WalkConfig) Validate() error {
-	if cfg.SearchDir == "" {
-		return errors.New("search dir is required")
+	cfg.SearchDirs = splitList(cfg.SearchDir)
+	cfg.Inputs = splitList(cfg.Input)
+
+	// stdin is never read implicitly, it is not a terminal under cron, ssh or in CI jobs either
+	// and might never be closed, which would block the run
+	if len(cfg.SearchDirs) == 0 && len(cfg.Inputs) == 0 {
+		cfg.SearchDirs = []string{"."}
 	}
 
-	fi, err := os.Stat(cfg.SearchDir)
-	if err != nil {
-		return fmt.Errorf("invalid search dir: %w", err)
+	for _, dir := range cfg.SearchDirs {
+		fi, err := os.Stat(dir)
+		if err != nil {
+			return fmt.Errorf("invalid search dir: %w", err)
+		}
+		if !fi.IsDir() {
+			return fmt.Errorf("search dir is not a directory: %s", dir)
+		}
 	}
-	if !fi.IsDir() {
-		return errors.New("search dir is not a directory")
+
+	stdin := 0
+	for _, input := range cfg.Inputs {
+		if input == fswalk.Stdin {
+			stdin++
+			continue
+		}
+		_, err := os.Stat(input)
+		if err != nil {
+			return fmt.Errorf("invalid input: %w", err)
+		}
+	}
+	if stdin > 1 {
+		return errors.New("stdin can only be read once")
 	}
 
 	if cfg.FileRegex == "" {
@@ -70,9 +120,53 @@ func (cfg *WalkConfig) Validate() error {
 		cfg.ArchiveRegexp = re
 	}
 
+	if cfg.ExcludeRegex != "" {
+		re, err = regexp.Compile(cfg.ExcludeRegex)
+		if err != nil {
+			return fmt.Errorf("invalid exclude regex: %w", err)
+		}
+		cfg.ExcludeRegexp = re
+	}
+
+	if cfg.ServerRegex != "" {
+		re, err = regexp.Compile(cfg.ServerRegex)
+		if err != nil {
+			return fmt.Errorf("invalid server regex: %w", err)
+		}
+		cfg.ServerRegexp = re
+	}
+
+	if cfg.MaxDepth < 0 {
+		return errors.New("max depth must not be negative")
+	}
+
+	if cfg.FollowInterval <= 0 {
+		return errors.New("follow interval must be greater than 0")
+	}
+
 	if cfg.Concurrency < 1 {
 		return errors.New("concurrency must be greater than 0")
 	}
 
 	return nil
 }
+
+// RepeatableFlags allows to pass the search dir, input and exclude regex flags multiple times.
+// Must be called after the flags of the config have been registered.
+func (cfg *WalkConfig) RepeatableFlags(fs *pflag.FlagSet) {
+	repeatable(fs, "search-dir", joinList)
+	repeatable(fs, "input", joinList)
+	repeatable(fs, "exclude-regex", joinRegex)
+}
+
+// splitList splits a comma separated list and removes empty and duplicate elements.
+func splitList(list string) []string {
+	result := make([]string, 0, 1)
+	for _, elem := range strings.Split(list, ",") {
+		elem = strings.TrimSpace(elem)
+		if elem != "" && !slices.Contains(result, elem) {
+			result = append(result, elem)
+		}
+	}
+	return result
+}
@@ -3,20 +3,33 @@ package sharedcontext
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
+	"log/slog"
 
 	"github.com/jxsl13/cli-config-boilerplate/cliconfig"
+	"github.com/jxsl13/twlog/internal/diag"
+	"github.com/jxsl13/twlog/internal/dialect"
 	"github.com/jxsl13/twlog/internal/sharedconfig"
 	"github.com/spf13/cobra"
 )
 
+// AnnotationFollow marks commands that support the follow mode when set to "true".
+const AnnotationFollow = "follow"
+
 func NewRoot(ctx context.Context) *Root {
-	ctx, cancelCause := context.WithCancelCause(ctx)
+	summary := diag.NewSummary()
+	ctx, cancelCause := context.WithCancelCause(diag.WithSummary(ctx, summary))
 	return &Root{
 		Ctx:         ctx,
 		CancelCause: cancelCause,
 		Format:      sharedconfig.NewFormatConfig(),
 		Walk:        sharedconfig.NewWalkConfig(),
+		Log:         sharedconfig.NewLogConfig(),
+		Filter:      sharedconfig.NewFilterConfig(),
+		Dialect:     sharedconfig.NewDialectConfig(),
+		Index:       sharedconfig.NewIndexConfig(),
+		Summary:     summary,
 	}
 }
 
@@ -25,24 +38,68 @@ type Root struct {
 	CancelCause context.CancelCauseFunc
 	Format      sharedconfig.FormatConfig
 	Walk        sharedconfig.WalkConfig
+	Log         sharedconfig.LogConfig
+	Filter      sharedconfig.FilterConfig
+	Dialect     sharedconfig.DialectConfig
+	Index       sharedconfig.IndexConfig
+	// Summary collects diagnostics that are logged at the end of the run
+	Summary *diag.Summary
 }
 
 func (cli *Root) PersistentPreRunE(cmd *cobra.Command) func(*cobra.Command, []string) error {
 	formatParser := cliconfig.RegisterFlags(&cli.Format, true, cmd, cliconfig.WithoutConfigFile())
 	walkParser := cliconfig.RegisterFlags(&cli.Walk, true, cmd)
+	cli.Walk.RepeatableFlags(cmd.PersistentFlags())
+	cmd.SetGlobalNormalizationFunc(sharedconfig.NormalizeFlagName)
+	logParser := cliconfig.RegisterFlags(&cli.Log, true, cmd, cliconfig.WithoutConfigFile())
+	filterParser := cliconfig.RegisterFlags(&cli.Filter, true, cmd, cliconfig.WithoutConfigFile())
+	dialectParser := cliconfig.RegisterFlags(&cli.Dialect, true, cmd, cliconfig.WithoutConfigFile())
+	indexParser := cliconfig.RegisterFlags(&cli.Index, true, cmd, cliconfig.WithoutConfigFile())
 	return func(cmd *cobra.Command, args []string) error {
 		log.SetOutput(cmd.ErrOrStderr()) // redirect log output to stderr
+		cli.Walk.Stdin = cmd.InOrStdin()
 
-		return errors.Join(
+		err := errors.Join(
 			formatParser(),
 			walkParser(),
+			logParser(),
+			filterParser(),
+			dialectParser(),
+			indexParser(),
 		)
+		if err != nil {
+			return err
+		}
+
+		// scanners of all commands parse the log lines with the selected dialect
+		cli.Dialect.Selection.Follow = cli.Walk.Follow
+		cli.Ctx = dialect.WithSelection(cli.Ctx, cli.Dialect.Selection)
+
+		// diagnostics are always logged to stderr in order to keep the output parsable
+		slog.SetDefault(cli.Log.NewLogger(cmd.ErrOrStderr()))
+
+		if cli.Walk.Follow {
+			return cli.validateFollow(cmd)
+		}
+		return nil
+	}
+}
+
+// validateFollow checks whether the executed command and the output format support the follow mode.
+func (cli *Root) validateFollow(cmd *cobra.Command) error {
+	if cmd.Annotations[AnnotationFollow] != "true" {
+		return fmt.Errorf("follow mode is not supported by the %s command", cmd.Name())
+	}
+	if !cli.Format.Streamable() {
+		return fmt.Errorf("output format %s cannot be followed", cli.Format.Output)
 	}
+	return nil
 }
 
 func (cli *Root) PersistentPostRunE(_ *cobra.Command) func(*cobra.Command, []string) error {
 	// could register stuff here
 	return func(cmd *cobra.Command, args []string) error {
+		cli.Summary.Log(cli.Ctx, slog.Default())
 		cli.CancelCause(context.Canceled) // cleanup only
 		return nil
 	}
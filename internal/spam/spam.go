@@ -0,0 +1,337 @@
+// Package spam detects spam waves, chat messages that are sent from many different IPs within a short time.
+package spam
+
+import (
+	"cmp"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jxsl13/twlog/model"
+	"github.com/jxsl13/twlog/stringutils"
+)
+
+const (
+	// SimilarityExact clusters messages that only differ in case and whitespace.
+	SimilarityExact = "exact"
+	// SimilarityMasked additionally ignores numbers and URLs.
+	SimilarityMasked = "masked"
+	// SimilarityJaccard clusters masked messages whose character n-grams are similar.
+	SimilarityJaccard = "jaccard"
+)
+
+var (
+	urlRegexp   = regexp.MustCompile(`^(?:(?:https?://|www\.)\S+|[a-z0-9-]+(?:\.[a-z0-9-]+)*\.[a-z]{2,}(?:/\S*)?)$`)
+	digitRegexp = regexp.MustCompile(`\d+`)
+)
+
+// spacePattern matches the white space that strings.Fields splits words at, \s only matches ASCII white space.
+const spacePattern = `[\s\v\x{85}\p{Z}]+`
+
+// Message is a chat message of a player.
+type Message struct {
+	File       string
+	LineNumber int
+	Time       time.Time
+	IP         string
+	Nickname   string
+	Text       string
+}
+
+// Config configures how messages are clustered and when a cluster is a spam wave.
+type Config struct {
+	Similarity string
+	// Threshold is the minimum jaccard similarity of two messages of the same cluster.
+	Threshold float64
+	// NGramSize is the length of the character n-grams that are compared by the jaccard similarity.
+	NGramSize int
+	// MinIPs is the minimum number of distinct IPs that must send a message of a cluster within Window.
+	MinIPs int
+	Window time.Duration
+}
+
+// token is a word of a normalized message.
+type token struct {
+	// key is compared in order to cluster messages
+	key string
+	// pattern is the regex that matches the word
+	pattern string
+}
+
+type message struct {
+	Message
+	tokens []token
+	key    string
+	ngrams map[string]bool
+}
+
+type cluster struct {
+	messages []*message
+}
+
+// Detect clusters the messages and returns the spam waves sorted by their start time.
+// Messages without timestamp or IP are ignored.
+func Detect(messages []Message, cfg Config) model.SpamWaveList {
+	msgs := make([]*message, 0, len(messages))
+	for _, m := range messages {
+		if m.Time.IsZero() || m.IP == "" {
+			continue
+		}
+		msgs = append(msgs, newMessage(m, cfg))
+	}
+
+	// deterministic clusters, no matter in which order the log files were searched
+	slices.SortStableFunc(msgs, func(a, b *message) int {
+		if c := a.Time.Compare(b.Time); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.File, b.File); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.LineNumber, b.LineNumber)
+	})
+
+	waves := make(model.SpamWaveList, 0, 4)
+	for _, c := range clusterMessages(msgs, cfg) {
+		waves = append(waves, c.waves(cfg)...)
+	}
+
+	slices.SortStableFunc(waves, func(a, b model.SpamWave) int {
+		if c := a.Start.Compare(b.Start); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Phrase, b.Phrase)
+	})
+	return waves
+}
+
+func newMessage(m Message, cfg Config) *message {
+	// the patterns are derived from the raw text, which who said matches against,
+	// only the keys, which are printed as phrase, make invisible characters visible
+	words := strings.Fields(strings.ToLower(m.Text))
+	tokens := make([]token, 0, len(words))
+	keys := make([]string, 0, len(words))
+	for _, word := range words {
+		t := newToken(word, cfg.Similarity != SimilarityExact)
+		t.key = stringutils.VisualizeInvisible(t.key)
+		tokens = append(tokens, t)
+		keys = append(keys, t.key)
+	}
+
+	msg := &message{
+		Message: m,
+		tokens:  tokens,
+		key:     strings.Join(keys, " "),
+	}
+	if cfg.Similarity == SimilarityJaccard {
+		msg.ngrams = ngrams(msg.key, cfg.NGramSize)
+	}
+	return msg
+}
+
+func newToken(word string, mask bool) token {
+	if !mask {
+		return token{key: word, pattern: regexp.QuoteMeta(word)}
+	}
+
+	if urlRegexp.MatchString(word) {
+		return token{key: "<url>", pattern: `\S+`}
+	}
+
+	var (
+		key     strings.Builder
+		pattern strings.Builder
+		last    = 0
+	)
+	for _, loc := range digitRegexp.FindAllStringIndex(word, -1) {
+		key.WriteString(word[last:loc[0]])
+		key.WriteString("<num>")
+		pattern.WriteString(regexp.QuoteMeta(word[last:loc[0]]))
+		pattern.WriteString(`\d+`)
+		last = loc[1]
+	}
+	key.WriteString(word[last:])
+	pattern.WriteString(regexp.QuoteMeta(word[last:]))
+	return token{key: key.String(), pattern: pattern.String()}
+}
+
+func ngrams(s string, n int) map[string]bool {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return map[string]bool{s: true}
+	}
+
+	result := make(map[string]bool, len(runes)-n+1)
+	for i := 0; i+n <= len(runes); i++ {
+		result[string(runes[i:i+n])] = true
+	}
+	return result
+}
+
+// jaccard returns the size of the intersection divided by the size of the union of both sets.
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+
+	intersection := 0
+	for k := range a {
+		if b[k] {
+			intersection++
+		}
+	}
+	return float64(intersection) / float64(len(a)+len(b)-intersection)
+}
+
+// clusterMessages groups messages with the same key or, in case of the jaccard similarity,
+// messages that are similar to the first message of a cluster.
+func clusterMessages(msgs []*message, cfg Config) []*cluster {
+	var (
+		clusters = make([]*cluster, 0, 64)
+		byKey    = make(map[string]*cluster, 64)
+	)
+
+	for _, m := range msgs {
+		if c, ok := byKey[m.key]; ok {
+			c.messages = append(c.messages, m)
+			continue
+		}
+
+		var found *cluster
+		if cfg.Similarity == SimilarityJaccard {
+			for _, c := range clusters {
+				if jaccard(c.messages[0].ngrams, m.ngrams) >= cfg.Threshold {
+					found = c
+					break
+				}
+			}
+		}
+		if found == nil {
+			found = &cluster{messages: make([]*message, 0, 1)}
+			clusters = append(clusters, found)
+		}
+		found.messages = append(found.messages, m)
+		byKey[m.key] = found
+	}
+	return clusters
+}
+
+// waves returns the messages that were sent by at least MinIPs distinct IPs within Window.
+// Flagged messages that are more than Window apart belong to different waves.
+func (c *cluster) waves(cfg Config) model.SpamWaveList {
+	var (
+		msgs    = c.messages
+		flagged = make([]bool, len(msgs))
+		ips     = make(map[string]int, 16)
+		left    = 0
+	)
+
+	// sliding window that ends at the right message
+	for right, m := range msgs {
+		ips[m.IP]++
+		for m.Time.Sub(msgs[left].Time) > cfg.Window {
+			ip := msgs[left].IP
+			ips[ip]--
+			if ips[ip] == 0 {
+				delete(ips, ip)
+			}
+			left++
+		}
+
+		if len(ips) >= cfg.MinIPs {
+			for i := left; i <= right; i++ {
+				flagged[i] = true
+			}
+		}
+	}
+
+	waves := make(model.SpamWaveList, 0, 1)
+	wave := make([]*message, 0, len(msgs))
+	for i, m := range msgs {
+		if !flagged[i] {
+			continue
+		}
+		if len(wave) > 0 && m.Time.Sub(wave[len(wave)-1].Time) > cfg.Window {
+			waves = append(waves, newWave(wave))
+			wave = wave[:0]
+		}
+		wave = append(wave, m)
+	}
+	if len(wave) > 0 {
+		waves = append(waves, newWave(wave))
+	}
+	return waves
+}
+
+func newWave(msgs []*message) model.SpamWave {
+	return model.SpamWave{
+		Phrase:    msgs[0].key,
+		Regex:     suggestRegex(msgs),
+		Start:     msgs[0].Time,
+		End:       msgs[len(msgs)-1].Time,
+		Messages:  len(msgs),
+		IPs:       distinct(msgs, func(m *message) string { return m.IP }),
+		Nicknames: distinct(msgs, func(m *message) string { return stringutils.VisualizeInvisible(m.Nickname) }),
+		Files:     distinct(msgs, func(m *message) string { return m.File }),
+	}
+}
+
+// suggestRegex returns a case insensitive regex that matches the text of all messages.
+// Messages with the same key match the exact sequence of words, otherwise all words of the first message
+// that occur in every message are matched in order.
+func suggestRegex(msgs []*message) string {
+	first := msgs[0]
+
+	sameKey := true
+	for _, m := range msgs[1:] {
+		if m.key != first.key {
+			sameKey = false
+			break
+		}
+	}
+
+	patterns := make([]string, 0, len(first.tokens))
+	if sameKey {
+		for _, t := range first.tokens {
+			patterns = append(patterns, t.pattern)
+		}
+		return `(?i)` + strings.Join(patterns, spacePattern)
+	}
+
+	for _, t := range first.tokens {
+		if containsInOrder(msgs, patterns, t) {
+			patterns = append(patterns, t.pattern)
+		}
+	}
+	if len(patterns) == 0 {
+		// nothing in common, fall back to the first message
+		for _, t := range first.tokens {
+			patterns = append(patterns, t.pattern)
+		}
+	}
+	return `(?i)` + strings.Join(patterns, `.*`)
+}
+
+// containsInOrder reports whether the regex of the previous patterns followed by t matches all messages.
+func containsInOrder(msgs []*message, patterns []string, t token) bool {
+	re, err := regexp.Compile(`(?i)` + strings.Join(append(slices.Clone(patterns), t.pattern), `.*`))
+	if err != nil {
+		return false
+	}
+	for _, m := range msgs {
+		if !re.MatchString(m.Text) {
+			return false
+		}
+	}
+	return true
+}
+
+func distinct(msgs []*message, value func(*message) string) []string {
+	values := make([]string, 0, len(msgs))
+	for _, m := range msgs {
+		values = append(values, value(m))
+	}
+	slices.Sort(values)
+	return slices.Compact(values)
+}
@@ -0,0 +1,138 @@
+package spam
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jxsl13/twlog/internal/matcher"
+)
+
+func TestDetect(t *testing.T) {
+	start := time.Date(2024, 5, 1, 18, 0, 0, 0, time.UTC)
+	message := func(minute int, ip, text string) Message {
+		return Message{
+			File:     "server.log",
+			Time:     start.Add(time.Duration(minute) * time.Minute),
+			IP:       ip,
+			Nickname: "bot",
+			Text:     text,
+		}
+	}
+
+	messages := []Message{
+		message(0, "1.1.1.1", "FREE skins at t.me/skins123 code 4411"),
+		message(1, "2.2.2.2", "free  skins at t.me/skins999 code 17"),
+		message(2, "3.3.3.3", "free skins at t.me/skins123 code 4411"),
+		message(2, "4.4.4.4", "free skins for everyone at t.me/x code 9"),
+		// same text, but too late for the first wave
+		message(30, "5.5.5.5", "free skins at t.me/skins123 code 4411"),
+		// no IP
+		message(2, "", "free skins at t.me/skins123 code 4411"),
+	}
+
+	tests := []struct {
+		similarity string
+		expected   string
+	}{
+		{SimilarityExact, ""},
+		{SimilarityMasked, "free skins at <url> code <num>: 1.1.1.1,2.2.2.2,3.3.3.3"},
+		{SimilarityJaccard, "free skins at <url> code <num>: 1.1.1.1,2.2.2.2,3.3.3.3,4.4.4.4"},
+	}
+
+	for _, test := range tests {
+		cfg := Config{
+			Similarity: test.similarity,
+			Threshold:  0.5,
+			NGramSize:  3,
+			MinIPs:     3,
+			Window:     5 * time.Minute,
+		}
+
+		waves := Detect(messages, cfg)
+		values := make([]string, 0, len(waves))
+		for _, w := range waves {
+			values = append(values, fmt.Sprintf("%s: %s", w.Phrase, strings.Join(w.IPs, ",")))
+
+			re, err := regexp.Compile(w.Regex)
+			if err != nil {
+				t.Fatalf("%s: invalid regex %q: %v", test.similarity, w.Regex, err)
+			}
+			for _, m := range messages {
+				if slices.Contains(w.IPs, m.IP) && !re.MatchString(m.Text) {
+					t.Errorf("%s: regex %q does not match %q", test.similarity, w.Regex, m.Text)
+				}
+			}
+		}
+
+		if actual := strings.Join(values, "\n"); actual != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.similarity, test.expected, actual)
+		}
+	}
+}
+
+func TestDetectInvisibleCharacters(t *testing.T) {
+	start := time.Date(2024, 5, 1, 18, 0, 0, 0, time.UTC)
+	texts := []string{
+		"free\u200bskins\u00a0at t.me/x",
+		"FREE\u200bSKINS\u00a0at t.me/x",
+		"free\u200bskins\u00a0at  t.me/x",
+	}
+
+	messages := make([]Message, 0, len(texts))
+	for i, text := range texts {
+		messages = append(messages, Message{
+			File: "server.log",
+			Time: start.Add(time.Duration(i) * time.Second),
+			IP:   fmt.Sprintf("%d.%d.%d.%d", i+1, i+1, i+1, i+1),
+			Text: text,
+		})
+	}
+
+	for _, similarity := range []string{SimilarityExact, SimilarityMasked} {
+		waves := Detect(messages, Config{Similarity: similarity, MinIPs: 3, Window: time.Minute})
+		if len(waves) != 1 {
+			t.Fatalf("%s: expected a single wave, got %d", similarity, len(waves))
+		}
+
+		// non-breaking spaces separate words like spaces
+		expected := `free\u200Bskins at`
+		if !strings.HasPrefix(waves[0].Phrase, expected) {
+			t.Errorf("%s: expected phrase with visible invisible characters %q, got %q", similarity, expected, waves[0].Phrase)
+		}
+
+		// the suggested regex is used as who said pattern, which matches the raw text
+		m, err := matcher.New([]string{waves[0].Regex}, false, false)
+		if err != nil {
+			t.Fatalf("%s: invalid regex %q: %v", similarity, waves[0].Regex, err)
+		}
+		for _, text := range texts {
+			if _, ok := m.Match(text); !ok {
+				t.Errorf("%s: regex %q does not match %q", similarity, waves[0].Regex, text)
+			}
+		}
+	}
+}
+
+func TestNewToken(t *testing.T) {
+	tests := []struct {
+		word    string
+		key     string
+		pattern string
+	}{
+		{"https://bot.xyz/a?b=1", "<url>", `\S+`},
+		{"t.me/freeskins", "<url>", `\S+`},
+		{"code4411x", "code<num>x", `code\d+x`},
+		{"a.b", "a.b", `a\.b`},
+	}
+
+	for _, test := range tests {
+		tok := newToken(test.word, true)
+		if tok.key != test.key || tok.pattern != test.pattern {
+			t.Errorf("%s: expected key %q and pattern %q, got %q and %q", test.word, test.key, test.pattern, tok.key, tok.pattern)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package stream
+
+import (
+	"context"
+	"io"
+
+	"github.com/jxsl13/twlog/fswalk"
+	"github.com/jxsl13/twlog/internal/sharedconfig"
+	"github.com/spf13/cobra"
+)
+
+// EmitFunc searches a single file and emits each result as soon as it has been found.
+// Results that belong together, e.g. because they share their context lines, are emitted at once.
+type EmitFunc[T comparable] func(filePath string, file io.Reader, emit func(...T) error) error
+
+// Follow follows all files like fswalk.Follow and prints each result as soon as it has been emitted.
+// Results that were already printed are skipped in case deduplicate is set.
+func Follow[T comparable](
+	ctx context.Context,
+	cmd *cobra.Command,
+	format *sharedconfig.FormatConfig,
+	walkCfg fswalk.WalkConfig,
+	deduplicate bool,
+	search EmitFunc[T],
+) error {
+	p := &printer[T]{
+		cmd:         cmd,
+		format:      format,
+		deduplicate: deduplicate,
+		seen:        make(map[T]struct{}, 64),
+	}
+
+	return fswalk.Follow(ctx, walkCfg, func(filePath string, file io.Reader) error {
+		return search(filePath, file, func(items ...T) error {
+			return p.Print(items)
+		})
+	})
+}
@@ -0,0 +1,140 @@
+package stream
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/jxsl13/twlog/fswalk"
+	"github.com/jxsl13/twlog/internal/sharedconfig"
+	"github.com/jxsl13/twlog/model"
+	"github.com/spf13/cobra"
+)
+
+// SearchFunc searches a single file and returns its results.
+type SearchFunc[T comparable] func(filePath string, file io.Reader) ([]T, error)
+
+// Walk walks all files like fswalk.Walk and prints the results of each file as soon as the file has been processed.
+// Results that were already printed are skipped in case deduplicate is set.
+// In case ordered output is configured, results are printed in the order of the walked files.
+func Walk[T comparable](
+	ctx context.Context,
+	cmd *cobra.Command,
+	format *sharedconfig.FormatConfig,
+	walkCfg fswalk.WalkConfig,
+	deduplicate bool,
+	search SearchFunc[T],
+) error {
+	p := &printer[T]{
+		cmd:         cmd,
+		format:      format,
+		deduplicate: deduplicate,
+		seen:        make(map[T]struct{}, 64),
+		pending:     make(map[string][]T, 16),
+	}
+
+	if format.Ordered {
+		walkCfg.Done = p.Flush
+	}
+
+	return fswalk.Walk(ctx, walkCfg, func(filePath string, file io.Reader) error {
+		items, err := search(filePath, file)
+		if err != nil {
+			return err
+		}
+
+		if format.Ordered {
+			p.Buffer(filePath, items)
+			return nil
+		}
+		return p.Print(items)
+	})
+}
+
+type printer[T comparable] struct {
+	mu          sync.Mutex
+	cmd         *cobra.Command
+	format      *sharedconfig.FormatConfig
+	deduplicate bool
+	// results that were already printed
+	seen map[T]struct{}
+	// file path -> results that are waiting for previous files to be printed
+	pending map[string][]T
+	// whether context lines were printed, which must be separated from the next ones
+	printedContext bool
+}
+
+func (p *printer[T]) Buffer(filePath string, items []T) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pending[filePath] = items
+}
+
+// Flush prints the buffered results of the given files.
+func (p *printer[T]) Flush(filePaths ...string) error {
+	for _, filePath := range filePaths {
+		p.mu.Lock()
+		items := p.pending[filePath]
+		delete(p.pending, filePath)
+		p.mu.Unlock()
+
+		err := p.Print(items)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Print prints the given results, which have been found in a single file.
+func (p *printer[T]) Print(items []T) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.deduplicate {
+		unseen := make([]T, 0, len(items))
+		for _, item := range items {
+			if _, ok := p.seen[item]; ok {
+				continue
+			}
+			p.seen[item] = struct{}{}
+			unseen = append(unseen, item)
+		}
+		items = unseen
+	}
+
+	// the text output prints shared context lines once, like the output of all results at once
+	if players, ok := any(items).([]model.PlayerExtended); ok && p.format.Output == sharedconfig.FormatText {
+		return p.printContext(players)
+	}
+
+	for _, item := range items {
+		err := p.format.PrintItem(p.cmd, item)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printContext prints players and their context lines in the text format.
+// Groups of players with context lines are separated by --, also across several calls.
+func (p *printer[T]) printContext(players model.PlayerExtendedList) error {
+	if len(players) == 0 {
+		return nil
+	}
+
+	out := p.cmd.OutOrStdout()
+	if players[0].Context != nil {
+		if p.printedContext {
+			_, err := io.WriteString(out, "--\n")
+			if err != nil {
+				return err
+			}
+		}
+		p.printedContext = true
+	}
+
+	_, err := io.WriteString(out, players.String())
+	return err
+}
@@ -0,0 +1,53 @@
+package testutils
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Output is a concurrency safe command output that allows to wait for results,
+// e.g. of commands that keep running in follow mode.
+type Output struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	written chan struct{}
+}
+
+func NewOutput() *Output {
+	return &Output{
+		written: make(chan struct{}, 1),
+	}
+}
+
+func (o *Output) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	n, err := o.buf.Write(p)
+	select {
+	case o.written <- struct{}{}:
+	default:
+	}
+	return n, err
+}
+
+func (o *Output) String() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.buf.String()
+}
+
+// WaitFor waits until the output contains s and returns false in case it does not before the timeout.
+func (o *Output) WaitFor(s string, timeout time.Duration) bool {
+	deadline := time.After(timeout)
+	for !strings.Contains(o.String(), s) {
+		select {
+		case <-o.written:
+		case <-deadline:
+			return false
+		}
+	}
+	return true
+}
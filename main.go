@@ -8,6 +8,9 @@ import (
 	"path/filepath"
 	"syscall"
 
+	"github.com/jxsl13/twlog/cmd/detect"
+	"github.com/jxsl13/twlog/cmd/index"
+	"github.com/jxsl13/twlog/cmd/stats"
 	"github.com/jxsl13/twlog/cmd/what"
 	"github.com/jxsl13/twlog/cmd/who"
 	"github.com/jxsl13/twlog/internal/sharedcontext"
@@ -38,5 +41,8 @@ func NewRootCmd(ctx context.Context) *cobra.Command {
 
 	cmd.AddCommand(who.NewWhoCommand(root))
 	cmd.AddCommand(what.NewWhatCommand(root))
+	cmd.AddCommand(stats.NewStatsCommand(root))
+	cmd.AddCommand(detect.NewDetectCommand(root))
+	cmd.AddCommand(index.NewIndexCommand(root))
 	return &cmd
 }
@@ -1,14 +1,24 @@
 package main
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/json"
 	"io"
+	"os"
+	"path/filepath"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/jxsl13/twlog/internal/testutils"
+	"github.com/jxsl13/twlog/model"
 )
 
 func TestWhoSaidCommand(t *testing.T) {
@@ -78,3 +88,1531 @@ func TestWhatSaidCommand(t *testing.T) {
 		t.Fatalf("expected some output, got nothing")
 	}
 }
+
+func TestWhoSaidTimeWindow(t *testing.T) {
+	ctx := context.TODO()
+	cmd := NewRootCmd(ctx)
+
+	archiveFolder := testutils.FilePath("testdata/subdir")
+
+	out, err := testutils.Execute(
+		cmd,
+		"--search-dir",
+		archiveFolder,
+		"who",
+		"said",
+		"--extended",
+		"--since",
+		"2024-05-01 19:00",
+		"--until",
+		"2024-05-01 19:31",
+		"te[il]egram",
+	)
+	if err != nil {
+		t.Fatalf("failed to execute command: %v", err)
+	}
+	data, err := io.ReadAll(out)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected exactly one line within the time window, got %d: %q", len(lines), lines)
+	}
+	if !strings.Contains(lines[0], `time="2024-05-01 19:30:01"`) {
+		t.Fatalf("expected timestamp in extended output, got %q", lines[0])
+	}
+}
+
+func TestWhoSessionsCommand(t *testing.T) {
+	ctx := context.TODO()
+	cmd := NewRootCmd(ctx)
+
+	out, err := testutils.Execute(
+		cmd,
+		"--search-dir",
+		testutils.FilePath("testdata"),
+		"who",
+		"sessions",
+		"--nickname",
+		"^OPlayer$",
+	)
+	if err != nil {
+		t.Fatalf("failed to execute command: %v", err)
+	}
+	data, err := io.ReadAll(out)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+
+	result := strings.TrimSpace(string(data))
+	if !strings.Contains(result, `duration=19m57s reason="Timeout"`) {
+		t.Fatalf("expected a closed session with leave reason, got %q", result)
+	}
+}
+
+func TestWhoSessionsNicknames(t *testing.T) {
+	ctx := context.TODO()
+
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "ddnet.log"), []byte(strings.Join([]string{
+		"2024-05-01 18:00:00 I server: player has entered the game. ClientID=0 addr=<{1.2.3.4:53212}> sevendown=0",
+		"2024-05-01 18:00:01 I chat: *** 'silent' entered and joined the game",
+		"2024-05-01 18:00:02 I server: player has entered the game. ClientID=1 addr=<{5.6.7.8:41234}> sevendown=0",
+		"2024-05-01 18:00:03 I chat: *** 'twin' entered and joined the game",
+		"2024-05-01 18:00:04 I server: player has entered the game. ClientID=2 addr=<{9.9.9.9:41234}> sevendown=0",
+		"2024-05-01 18:00:05 I chat: *** 'other' entered and joined the game",
+		"2024-05-01 18:00:06 I chat: 2:-2:twin: hi",
+		// two players are known as twin, the name change must not be attributed to the wrong one
+		"2024-05-01 18:00:07 I chat: *** 'twin' changed name to 'renamed'",
+		"2024-05-01 18:00:08 I chat: 2:-2:renamed: hi",
+		"",
+	}, "\n")), 0o644)
+	if err != nil {
+		t.Fatalf("failed to write log file: %v", err)
+	}
+
+	tests := []struct {
+		nickname string
+		expected []string
+	}{
+		// players that never chat are known by the enter line
+		{"^silent$", []string{"1.2.3.4"}},
+		{"^renamed$", []string{"9.9.9.9"}},
+		{"^twin$", []string{"5.6.7.8", "9.9.9.9"}},
+	}
+	for _, test := range tests {
+		out, err := testutils.Execute(
+			NewRootCmd(ctx),
+			"--search-dir",
+			dir,
+			"--output",
+			"template",
+			"--template",
+			"{{.IP}}",
+			"who",
+			"sessions",
+			"--nickname",
+			test.nickname,
+		)
+		if err != nil {
+			t.Fatalf("%s: failed to execute command: %v", test.nickname, err)
+		}
+		if actual := strings.Fields(out.String()); !slices.Equal(actual, test.expected) {
+			t.Fatalf("%s: expected %v, got %v", test.nickname, test.expected, actual)
+		}
+	}
+}
+
+func TestWhoBannedCommand(t *testing.T) {
+	ctx := context.TODO()
+	cmd := NewRootCmd(ctx)
+
+	out, err := testutils.Execute(
+		cmd,
+		"--search-dir",
+		testutils.FilePath("testdata"),
+		"who",
+		"banned",
+		"--ip",
+		"20.0.0.0/24",
+	)
+	if err != nil {
+		t.Fatalf("failed to execute command: %v", err)
+	}
+	data, err := io.ReadAll(out)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected a ban and an unban, got %d lines: %q", len(lines), lines)
+	}
+	if !strings.Contains(lines[0], `duration=5m0s reason="spam" by="vote called by admin (spam)"`) {
+		t.Fatalf("expected ban triggered by vote, got %q", lines[0])
+	}
+	if !strings.Contains(lines[1], `action=unban`) || !strings.Contains(lines[1], `by="rcon id=0 name=admin"`) {
+		t.Fatalf("expected unban triggered by rcon, got %q", lines[1])
+	}
+}
+
+func TestWhoJoinedCommand(t *testing.T) {
+	ctx := context.TODO()
+	cmd := NewRootCmd(ctx)
+
+	out, err := testutils.Execute(
+		cmd,
+		"--search-dir",
+		testutils.FilePath("testdata"),
+		"who",
+		"joined",
+		"--country",
+		"276",
+		"--clan",
+		"^zC$",
+	)
+	if err != nil {
+		t.Fatalf("failed to execute command: %v", err)
+	}
+	data, err := io.ReadAll(out)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+
+	result := strings.TrimSpace(string(data))
+	if strings.Count(result, "\n") != 0 || !strings.Contains(result, `ip=10.0.0.2 port=8303 version=1796 name="silent"`) {
+		t.Fatalf("expected exactly the silent zCatch player, got %q", result)
+	}
+}
+
+func TestWhoAliasesCommand(t *testing.T) {
+	ctx := context.TODO()
+	cmd := NewRootCmd(ctx)
+
+	out, err := testutils.Execute(
+		cmd,
+		"--search-dir",
+		testutils.FilePath("testdata"),
+		"--output",
+		"dot",
+		"who",
+		"aliases",
+		"b0t",
+	)
+	if err != nil {
+		t.Fatalf("failed to execute command: %v", err)
+	}
+	data, err := io.ReadAll(out)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+
+	result := string(data)
+	for _, expected := range []string{
+		`"nickname:b0t" -- "ip:5.6.7.8"`,
+		`"nickname:bot" -- "ip:5.6.7.8"`,
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("expected %q in graph, got %q", expected, result)
+		}
+	}
+}
+
+func TestWhoSaidStream(t *testing.T) {
+	ctx := context.TODO()
+	cmd := NewRootCmd(ctx)
+
+	out, err := testutils.Execute(
+		cmd,
+		"--search-dir",
+		testutils.FilePath("testdata"),
+		"--output",
+		"json",
+		"--stream",
+		"--ordered",
+		"who",
+		"said",
+		"--ips-only",
+		"--deduplicate",
+		"te[il]egram",
+	)
+	if err != nil {
+		t.Fatalf("failed to execute command: %v", err)
+	}
+	data, err := io.ReadAll(out)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	expected := []string{`"5.6.7.8"`, `"2001:db8::7"`, `"9.10.11.12"`}
+	if strings.Join(lines, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected newline delimited json %q, got %q", expected, lines)
+	}
+}
+
+func TestWhoSaidCSV(t *testing.T) {
+	ctx := context.TODO()
+	cmd := NewRootCmd(ctx)
+
+	out, err := testutils.Execute(
+		cmd,
+		"--search-dir",
+		testutils.FilePath("testdata"),
+		"--output",
+		"csv",
+		"who",
+		"said",
+		"--extended",
+		"--until",
+		"2024-05-01 18:05",
+		"te[il]egram",
+	)
+	if err != nil {
+		t.Fatalf("failed to execute command: %v", err)
+	}
+	data, err := io.ReadAll(out)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+
+	// the matched pattern is only reported for several patterns
+	expected := "file,time,nickname,id,ip,text\n" +
+		testutils.FilePath("testdata/subdir/ddnet.log") + ",2024-05-01 18:01:13,bot,1,5.6.7.8,join our telegram t.me/freeskins\n"
+	if string(data) != expected {
+		t.Fatalf("expected %q, got %q", expected, string(data))
+	}
+}
+
+func TestWhoSaidTemplate(t *testing.T) {
+	ctx := context.TODO()
+	cmd := NewRootCmd(ctx)
+
+	out, err := testutils.Execute(
+		cmd,
+		"--search-dir",
+		testutils.FilePath("testdata"),
+		"--output",
+		"template",
+		"--template",
+		`ban {{.IP | cidr 24}} 60 {{.Text | quote}}`,
+		"who",
+		"said",
+		"--extended",
+		"--since",
+		"2024-05-01 19:00",
+		"spam|skins on",
+	)
+	if err != nil {
+		t.Fatalf("failed to execute command: %v", err)
+	}
+	data, err := io.ReadAll(out)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+
+	expected := "ban 9.10.11.0/24 60 \"free skins on telegram\"\n"
+	if string(data) != expected {
+		t.Fatalf("expected %q, got %q", expected, string(data))
+	}
+}
+
+func TestWhoJoinedBanList(t *testing.T) {
+	ctx := context.TODO()
+	cmd := NewRootCmd(ctx)
+
+	out, err := testutils.Execute(
+		cmd,
+		"--search-dir",
+		testutils.FilePath("testdata"),
+		"--output",
+		"ddnet-ban",
+		"--ban-duration",
+		"2h",
+		"--ban-reason",
+		"ban evasion",
+		"--ban-collapse-threshold",
+		"2",
+		"who",
+		"joined",
+		"--ip",
+		"20.0.0.0/8",
+	)
+	if err != nil {
+		t.Fatalf("failed to execute command: %v", err)
+	}
+	data, err := io.ReadAll(out)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+
+	expected := "ban_range 20.0.0.0 20.0.0.255 120 ban evasion\n"
+	if string(data) != expected {
+		t.Fatalf("expected %q, got %q", expected, string(data))
+	}
+}
+
+func TestWhoSaidIPsOnlyBanList(t *testing.T) {
+	ctx := context.TODO()
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{"ddnet-ban", "ban 9.10.11.12 1440 banned by twlog"},
+		{"nftables", "9.10.11.12"},
+		{"ipset", "add twlog-v4 9.10.11.12"},
+		{"fail2ban", "twlog: ban 9.10.11.12 reason="},
+	}
+
+	for _, test := range tests {
+		// who said returns plain IP addresses and what said IP addresses with text
+		for _, args := range [][]string{
+			{"who", "said", "--ips-only", "free skins on"},
+			{"what", "said", "--ips-only", "spam"},
+		} {
+			out, err := testutils.Execute(
+				NewRootCmd(ctx),
+				append([]string{
+					"--input",
+					testutils.FilePath("testdata/subdir/ddnet.log"),
+					"--output",
+					test.format,
+				}, args...)...,
+			)
+			if err != nil {
+				t.Fatalf("%s %v: failed to execute command: %v", test.format, args, err)
+			}
+			if actual := out.String(); !strings.Contains(actual, test.expected) {
+				t.Fatalf("%s %v: expected %q in %q", test.format, args, test.expected, actual)
+			}
+		}
+	}
+}
+
+func TestWhoSaidStdin(t *testing.T) {
+	ctx := context.TODO()
+	cmd := NewRootCmd(ctx)
+
+	data, err := os.ReadFile(testutils.FilePath("testdata/subdir/ddnet.log"))
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	cmd.SetIn(bytes.NewReader(data))
+
+	out, err := testutils.Execute(
+		cmd,
+		"--input",
+		"-",
+		"who",
+		"said",
+		"free skins on",
+	)
+	if err != nil {
+		t.Fatalf("failed to execute command: %v", err)
+	}
+	result, err := io.ReadAll(out)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+
+	expected := "<{9.10.11.12}> spam: free skins on telegram"
+	if actual := strings.TrimSpace(string(result)); actual != expected {
+		t.Fatalf("expected %q, got %q", expected, actual)
+	}
+}
+
+func TestWhoSaidIgnoresStdinWithoutInput(t *testing.T) {
+	ctx := context.TODO()
+	cmd := NewRootCmd(ctx)
+
+	// stdin is not a terminal under cron or in CI jobs, it must not replace the current directory
+	cmd.SetIn(strings.NewReader("[2024-05-01 18:00:00][chat]: 0:-2:stdin: free skins on stdin\n"))
+
+	out, err := testutils.Execute(
+		cmd,
+		"who",
+		"said",
+		"--fixed-strings",
+		"free skins on",
+	)
+	if err != nil {
+		t.Fatalf("failed to execute command: %v", err)
+	}
+
+	result := strings.TrimSpace(out.String())
+	if strings.Contains(result, "stdin") || !strings.Contains(result, "<{9.10.11.12}> spam: free skins on telegram") {
+		t.Fatalf("expected the current directory to be searched instead of stdin, got %q", result)
+	}
+}
+
+func TestWhoBannedInputFiles(t *testing.T) {
+	ctx := context.TODO()
+	cmd := NewRootCmd(ctx)
+
+	out, err := testutils.Execute(
+		cmd,
+		"--input",
+		testutils.FilePath("testdata/subdir/ddnet.log")+","+testutils.FilePath("testdata/moderation.log"),
+		"--output",
+		"ddnet-ban",
+		"who",
+		"banned",
+	)
+	if err != nil {
+		t.Fatalf("failed to execute command: %v", err)
+	}
+	data, err := io.ReadAll(out)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+
+	expected := "ban 20.0.0.2 1440 banned by twlog\nban 30.0.0.1 1440 banned by twlog\n"
+	if string(data) != expected {
+		t.Fatalf("expected %q, got %q", expected, string(data))
+	}
+}
+
+func TestWalkMultipleSearchDirs(t *testing.T) {
+	ctx := context.TODO()
+	cmd := NewRootCmd(ctx)
+
+	out, err := testutils.Execute(
+		cmd,
+		"--search-dir",
+		testutils.FilePath("testdata"),
+		"--search-dir",
+		testutils.FilePath("testdata/subdir"),
+		"--max-depth",
+		"1",
+		"--exclude-regex",
+		"moderation",
+		"--exclude-regex",
+		`ddnet\.log$`,
+		"--output",
+		"template",
+		"--template",
+		"{{.IP}}",
+		"who",
+		"joined",
+	)
+	if err != nil {
+		t.Fatalf("failed to execute command: %v", err)
+	}
+	data, err := io.ReadAll(out)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+
+	expected := "10.0.0.2\n2001:db8::9\n"
+	if string(data) != expected {
+		t.Fatalf("expected %q, got %q", expected, string(data))
+	}
+}
+
+func TestWalkExcludeRelativeToSearchDir(t *testing.T) {
+	ctx := context.TODO()
+
+	// the search dir itself contains the excluded word
+	root := filepath.Join(t.TempDir(), "backup-srv")
+	files := map[string]string{
+		"logs/server.log": "2024-05-01 18:01:12 I server: player has entered the game. ClientID=1 addr=<{1.2.3.4:41234}> sevendown=0\n",
+		"backup/old.log":  "2024-04-01 18:01:12 I server: player has entered the game. ClientID=1 addr=<{5.6.7.8:41234}> sevendown=0\n",
+		"logs/backup.log": "2024-04-01 18:01:12 I server: player has entered the game. ClientID=1 addr=<{9.9.9.9:41234}> sevendown=0\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		if err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		err = os.WriteFile(path, []byte(content), 0o644)
+		if err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		exclude  string
+		expected string
+	}{
+		{"backup", "1.2.3.4"},
+		{"^/backup/", "9.9.9.9\n1.2.3.4"},
+	}
+	for _, test := range tests {
+		out, err := testutils.Execute(
+			NewRootCmd(ctx),
+			"--search-dir",
+			root,
+			"--exclude-regex",
+			test.exclude,
+			"--output",
+			"template",
+			"--template",
+			"{{.IP}}",
+			"who",
+			"joined",
+		)
+		if err != nil {
+			t.Fatalf("%s: failed to execute command: %v", test.exclude, err)
+		}
+		if actual := strings.TrimSpace(out.String()); actual != test.expected {
+			t.Fatalf("%s: expected %q, got %q", test.exclude, test.expected, actual)
+		}
+	}
+}
+
+func TestWalkFollowSymlinks(t *testing.T) {
+	ctx := context.TODO()
+
+	dir := t.TempDir()
+	err := os.Symlink(testutils.FilePath("testdata/subdir"), filepath.Join(dir, "link"))
+	if err != nil {
+		t.Skipf("symlinks are not supported: %v", err)
+	}
+
+	// --follow-symlinks is the former name of --dereference
+	for _, flag := range []string{"--dereference", "--follow-symlinks", "-L"} {
+		out, err := testutils.Execute(NewRootCmd(ctx), "--search-dir", dir, flag, "who", "said", "free skins on")
+		if err != nil {
+			t.Fatalf("%s: failed to execute command: %v", flag, err)
+		}
+		expected := "<{9.10.11.12}> spam: free skins on telegram"
+		if actual := strings.TrimSpace(out.String()); actual != expected {
+			t.Fatalf("%s: expected %q, got %q", flag, expected, actual)
+		}
+	}
+}
+
+func TestWhoSaidFollow(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cmd := NewRootCmd(ctx)
+
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "server.log")
+	err := os.WriteFile(logFile, []byte(
+		"2024-05-01 18:01:12 I server: player has entered the game. ClientID=1 addr=<{5.6.7.8:41234}> sevendown=0\n",
+	), 0o644)
+	if err != nil {
+		t.Fatalf("failed to write log file: %v", err)
+	}
+
+	out := testutils.NewOutput()
+	cmd.SetOut(out)
+	cmd.SetArgs([]string{
+		"--search-dir",
+		dir,
+		"--follow",
+		"--poll-interval",
+		"10ms",
+		"who",
+		"said",
+		"telegram",
+	})
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Execute()
+	}()
+
+	// lines that are appended before the file is opened are found by the initial read
+	f, err := os.OpenFile(logFile, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("failed to open log file: %v", err)
+	}
+	_, err = f.WriteString("2024-05-01 18:01:13 I chat: 1:-2:bot: join our telegram t.me/freeskins\n")
+	f.Close()
+	if err != nil {
+		t.Fatalf("failed to append to log file: %v", err)
+	}
+
+	expected := "<{5.6.7.8}> bot: join our telegram t.me/freeskins\n"
+	if !out.WaitFor(expected, 10*time.Second) {
+		t.Fatalf("expected %q, got %q", expected, out.String())
+	}
+	cancel()
+
+	err = <-done
+	if err != nil {
+		t.Fatalf("failed to execute command: %v", err)
+	}
+	if out.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestWhoSaidFollowDialectFile(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cmd := NewRootCmd(ctx)
+
+	dir := t.TempDir()
+	logDir := filepath.Join(dir, "logs")
+	err := os.Mkdir(logDir, 0o755)
+	if err != nil {
+		t.Fatalf("failed to create log dir: %v", err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "dialects.yaml"), []byte("dialects:\n"+
+		"  - name: fork\n"+
+		"    join: '\\[fork\\] join (?P<id>\\d+) (?P<ip>\\S+) ''(?P<name>.*)'''\n"+
+		"    chat: '\\[fork\\] (?P<name>.+) \\((?P<id>\\d+)\\): (?P<text>.+)'\n",
+	), 0o644)
+	if err != nil {
+		t.Fatalf("failed to write dialect file: %v", err)
+	}
+	// far less lines than the auto-detection reads ahead
+	err = os.WriteFile(filepath.Join(logDir, "fork.log"), []byte(
+		"2024-05-01 18:01:12 [fork] join 1 5.6.7.8:8303 'bot'\n"+
+			"2024-05-01 18:01:13 [fork] bot (1): join our telegram\n",
+	), 0o644)
+	if err != nil {
+		t.Fatalf("failed to write log file: %v", err)
+	}
+
+	out := testutils.NewOutput()
+	cmd.SetOut(out)
+	cmd.SetArgs([]string{
+		"--search-dir",
+		logDir,
+		"--dialect-file",
+		filepath.Join(dir, "dialects.yaml"),
+		"--follow",
+		"--poll-interval",
+		"10ms",
+		"who",
+		"said",
+		"telegram",
+	})
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Execute()
+	}()
+
+	expected := "<{5.6.7.8}> bot: join our telegram\n"
+	if !out.WaitFor(expected, 5*time.Second) {
+		t.Fatalf("expected %q, got %q", expected, out.String())
+	}
+	cancel()
+
+	err = <-done
+	if err != nil {
+		t.Fatalf("failed to execute command: %v", err)
+	}
+}
+
+func TestWhoSaidServerRotation(t *testing.T) {
+	ctx := context.TODO()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		// rotated file, its name sorts after the new file
+		"srv1/server_b.log": "2024-05-01 18:01:12 I server: player has entered the game. ClientID=1 addr=<{5.6.7.8:41234}> sevendown=0\n" +
+			"2024-05-01 18:01:13 I chat: 1:-2:bot: hi\n",
+		"srv1/server_a.log": "2024-05-01 20:00:00 I chat: 1:-2:bot: join our telegram t.me/freeskins\n",
+		// other server that uses the same client id
+		"srv2/server.log": "2024-05-01 17:00:00 I server: player has entered the game. ClientID=1 addr=<{1.2.3.4:41234}> sevendown=0\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		if err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		err = os.WriteFile(path, []byte(content), 0o644)
+		if err != nil {
+			t.Fatalf("failed to write log file: %v", err)
+		}
+	}
+
+	for _, concurrency := range []string{"1", "4"} {
+		cmd := NewRootCmd(ctx)
+		out, err := testutils.Execute(
+			cmd,
+			"--search-dir",
+			dir,
+			"--server-regex",
+			`/(srv\d+)/`,
+			"--concurrency",
+			concurrency,
+			"who",
+			"said",
+			"telegram",
+		)
+		if err != nil {
+			t.Fatalf("failed to execute command: %v", err)
+		}
+
+		expected := "<{5.6.7.8}> bot: join our telegram t.me/freeskins"
+		if actual := strings.TrimSpace(out.String()); actual != expected {
+			t.Fatalf("concurrency %s: expected %q, got %q", concurrency, expected, actual)
+		}
+	}
+}
+
+func TestWhoSaidServerArchiveEntries(t *testing.T) {
+	ctx := context.TODO()
+
+	type entry struct {
+		name    string
+		modTime time.Time
+		content string
+	}
+	writeTarGz := func(path string, entries ...entry) {
+		t.Helper()
+		var buf bytes.Buffer
+		gw := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gw)
+		for _, e := range entries {
+			err := tw.WriteHeader(&tar.Header{Name: e.name, Mode: 0o644, Size: int64(len(e.content)), ModTime: e.modTime})
+			if err != nil {
+				t.Fatalf("failed to write tar header: %v", err)
+			}
+			_, err = io.WriteString(tw, e.content)
+			if err != nil {
+				t.Fatalf("failed to write tar entry: %v", err)
+			}
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatalf("failed to close tar writer: %v", err)
+		}
+		if err := gw.Close(); err != nil {
+			t.Fatalf("failed to close gzip writer: %v", err)
+		}
+		if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+			t.Fatalf("failed to write archive: %v", err)
+		}
+	}
+
+	var (
+		dir   = t.TempDir()
+		older = time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+		newer = older.Add(24 * time.Hour)
+	)
+	// both archives contain a log file of the same server, which must not share their state
+	// while the archives are searched concurrently
+	writeTarGz(filepath.Join(dir, "a.tar.gz"),
+		entry{"srv1.log", newer, "2024-05-02 18:00:00 I server: player has entered the game. ClientID=1 addr=<{1.1.1.1:41234}> sevendown=0\n" +
+			"2024-05-02 18:00:01 I chat: 1:-2:a: telegram a\n"},
+		// rotated entry with a previous connection of the same client id, it is older than the entry before
+		entry{"srv1-old.log", older, "2024-05-01 18:00:02 I chat: 1:-2:old: telegram old\n"},
+	)
+	writeTarGz(filepath.Join(dir, "b.tar.gz"),
+		entry{"srv1-old.log", older, "2024-05-01 18:00:00 I server: player has entered the game. ClientID=1 addr=<{2.2.2.2:41234}> sevendown=0\n"},
+		// chronological rotation, the state is carried over
+		entry{"srv1.log", newer, "2024-05-02 18:00:01 I chat: 1:-2:b: telegram b\n"},
+	)
+
+	for _, concurrency := range []string{"1", "4"} {
+		out, err := testutils.Execute(
+			NewRootCmd(ctx),
+			"--search-dir", dir,
+			"--include-archive",
+			"--server-regex", `@(srv\d+)`,
+			"--concurrency", concurrency,
+			"who",
+			"said",
+			"telegram",
+		)
+		if err != nil {
+			t.Fatalf("failed to execute command: %v", err)
+		}
+
+		// the archives are searched concurrently
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		slices.Sort(lines)
+
+		expected := "<{1.1.1.1}> a: telegram a\n<{2.2.2.2}> b: telegram b"
+		if actual := strings.Join(lines, "\n"); actual != expected {
+			t.Fatalf("concurrency %s: expected %q, got %q", concurrency, expected, actual)
+		}
+	}
+}
+
+func TestWhoSaidDiagnostics(t *testing.T) {
+	ctx := context.TODO()
+
+	dir := t.TempDir()
+	content := "2024-05-01 18:01:12 I server: player has entered the game. ClientID=1 addr=<{5.6.7.8:41234}> sevendown=0\n" +
+		"garbage that is no log line\n" +
+		"2024-05-01 18:01:13 I chat: 1:-2:bot: join our telegram\n" +
+		"2024-05-01 18:01:14 I chat: 2:-2:ghost: telegram too\n"
+	err := os.WriteFile(filepath.Join(dir, "server.log"), []byte(content), 0o644)
+	if err != nil {
+		t.Fatalf("failed to write log file: %v", err)
+	}
+
+	stderr := bytes.NewBuffer(nil)
+	cmd := NewRootCmd(ctx)
+	cmd.SetErr(stderr)
+	out, err := testutils.Execute(
+		cmd,
+		"--search-dir",
+		dir,
+		"--log-format",
+		"json",
+		"--log-level",
+		"debug",
+		"who",
+		"said",
+		"telegram",
+	)
+	if err != nil {
+		t.Fatalf("failed to execute command: %v", err)
+	}
+
+	expected := "<{5.6.7.8}> bot: join our telegram"
+	if actual := strings.TrimSpace(out.String()); actual != expected {
+		t.Fatalf("expected %q, got %q", expected, actual)
+	}
+
+	diagnostics := stderr.String()
+	for _, expected := range []string{
+		`"msg":"could not find join line"`,
+		`"msg":"summary","files_scanned":1,"archives_skipped":0,"orphan_chat_lines":1,"parse_failures":1`,
+	} {
+		if !strings.Contains(diagnostics, expected) {
+			t.Fatalf("expected diagnostics to contain %q, got:\n%s", expected, diagnostics)
+		}
+	}
+}
+
+func TestStatsCommand(t *testing.T) {
+	ctx := context.TODO()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.log": "2024-05-01 18:01:12 I server: player has entered the game. ClientID=1 addr=<{5.6.7.8:41234}> sevendown=0\n" +
+			"2024-05-01 18:01:13 I chat: 1:-2:bot: Join  our TELEGRAM\n" +
+			"2024-05-01 18:02:13 I chat: 1:-2:bot: join our telegram\n",
+		"b.log": "2024-05-01 20:00:00 I server: player has entered the game. ClientID=0 addr=<{5.6.7.8:41234}> sevendown=0\n" +
+			"2024-05-01 20:00:01 I server: player has entered the game. ClientID=1 addr=<{1.2.3.4:41234}> sevendown=0\n" +
+			// DDNet join lines do not contain the nickname, it is known from enter and chat lines
+			"2024-05-01 20:00:01 I chat: *** 'bot' entered and joined the game\n" +
+			"2024-05-01 20:00:02 I chat: 1:-2:nameless tee: hi\n",
+	}
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)
+		if err != nil {
+			t.Fatalf("failed to write log file: %v", err)
+		}
+	}
+
+	cmd := NewRootCmd(ctx)
+	out, err := testutils.Execute(
+		cmd,
+		"--search-dir",
+		dir,
+		"--output",
+		"csv",
+		"stats",
+		"--top",
+		"1",
+	)
+	if err != nil {
+		t.Fatalf("failed to execute command: %v", err)
+	}
+
+	expected := strings.Join([]string{
+		"statistic,rank,key,count",
+		"total,1,messages,3",
+		"total,2,joins,3",
+		"total,3,unique_ips,2",
+		"total,4,unique_chatters,2",
+		"total,5,active_files,2",
+		"chatters,1,bot,2",
+		"ips,1,5.6.7.8,2",
+		"nickname_ips,1,bot,2",
+		"phrases,1,join our telegram,2",
+		"hours,1,18,2",
+		"file_messages,1," + filepath.Join(dir, "a.log") + ",2",
+		"file_joins,1," + filepath.Join(dir, "b.log") + ",2",
+	}, "\n")
+	if actual := strings.TrimSpace(out.String()); actual != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, actual)
+	}
+}
+
+func TestDetectSpamCommand(t *testing.T) {
+	ctx := context.TODO()
+
+	dir := t.TempDir()
+	content := "2024-05-01 18:00:00 I server: player has entered the game. ClientID=1 addr=<{1.1.1.1:41234}> sevendown=0\n" +
+		"2024-05-01 18:00:00 I server: player has entered the game. ClientID=2 addr=<{2.2.2.2:41234}> sevendown=0\n" +
+		"2024-05-01 18:00:00 I server: player has entered the game. ClientID=3 addr=<{3.3.3.3:41234}> sevendown=0\n" +
+		"2024-05-01 18:01:00 I chat: 1:-2:bot1: FREE skins at t.me/skins123\n" +
+		"2024-05-01 18:01:30 I chat: 2:-2:bot2: free skins at t.me/skins999\n" +
+		"2024-05-01 18:02:00 I chat: 3:-2:bot3: free skins at https://t.me/x\n" +
+		"2024-05-01 18:02:00 I chat: 3:-2:bot3: hi\n"
+	err := os.WriteFile(filepath.Join(dir, "server.log"), []byte(content), 0o644)
+	if err != nil {
+		t.Fatalf("failed to write log file: %v", err)
+	}
+
+	cmd := NewRootCmd(ctx)
+	out, err := testutils.Execute(
+		cmd,
+		"--search-dir",
+		dir,
+		"--output",
+		"json",
+		"detect",
+		"spam",
+		"--min-ips",
+		"3",
+		"--window",
+		"2m",
+	)
+	if err != nil {
+		t.Fatalf("failed to execute command: %v", err)
+	}
+
+	var waves []struct {
+		Regex string   `json:"regex"`
+		IPs   []string `json:"ips"`
+	}
+	err = json.Unmarshal(out.Bytes(), &waves)
+	if err != nil {
+		t.Fatalf("failed to unmarshal output: %v", err)
+	}
+	if len(waves) != 1 {
+		t.Fatalf("expected one spam wave, got %d: %s", len(waves), out.String())
+	}
+	if actual := strings.Join(waves[0].IPs, ","); actual != "1.1.1.1,2.2.2.2,3.3.3.3" {
+		t.Fatalf("expected all three IPs, got %q", actual)
+	}
+
+	// the suggested regex finds the spam bots
+	cmd = NewRootCmd(ctx)
+	out, err = testutils.Execute(
+		cmd,
+		"--search-dir",
+		dir,
+		"who",
+		"said",
+		"-i",
+		waves[0].Regex,
+	)
+	if err != nil {
+		t.Fatalf("failed to execute command: %v", err)
+	}
+
+	expected := "1.1.1.1\n2.2.2.2\n3.3.3.3"
+	if actual := strings.TrimSpace(out.String()); actual != expected {
+		t.Fatalf("expected %q, got %q", expected, actual)
+	}
+}
+
+func TestWhereFilter(t *testing.T) {
+	ctx := context.TODO()
+	archiveFolder := testutils.FilePath("testdata")
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{
+			[]string{"who", "said", "."},
+			"<{20.0.0.2}> spammer: buy gold\n<{20.0.0.2}> spammer: buy gold again",
+		},
+		{
+			[]string{"what", "said", "spam"},
+			"<{20.0.0.2}> spammer: buy gold\n<{20.0.0.2}> spammer: buy gold again",
+		},
+		{
+			[]string{"who", "banned", "--ip", "20.0.0.2"},
+			"",
+		},
+	}
+
+	for _, test := range tests {
+		cmd := NewRootCmd(ctx)
+		args := append([]string{
+			"--search-dir",
+			archiveFolder,
+			"--concurrency",
+			"1",
+			"--where",
+			`nick =~ "^spam" && text !~ '^\s*$' && ip in 20.0.0.0/8 && time < "2024-05-02 20:01:10"`,
+		}, test.args...)
+
+		out, err := testutils.Execute(cmd, args...)
+		if err != nil {
+			t.Fatalf("%v: failed to execute command: %v", test.args, err)
+		}
+
+		if actual := strings.TrimSpace(out.String()); actual != test.expected {
+			t.Fatalf("%v: expected %q, got %q", test.args, test.expected, actual)
+		}
+	}
+
+	cmd := NewRootCmd(ctx)
+	_, err := testutils.Execute(cmd, "--search-dir", archiveFolder, "--where", "nick ==", "who", "said", ".")
+	if err == nil {
+		t.Fatal("expected an error for an invalid where expression")
+	}
+}
+
+func TestWhoSaidPatternsFile(t *testing.T) {
+	ctx := context.TODO()
+
+	patternsFile := filepath.Join(t.TempDir(), "patterns.txt")
+	err := os.WriteFile(patternsFile, []byte("T.ME/FREESKINS\r\n\nBUY GOLD\n"), 0o644)
+	if err != nil {
+		t.Fatalf("failed to write patterns file: %v", err)
+	}
+
+	cmd := NewRootCmd(ctx)
+	out, err := testutils.Execute(
+		cmd,
+		"--search-dir",
+		testutils.FilePath("testdata"),
+		"--concurrency",
+		"1",
+		"--output",
+		"csv",
+		"who",
+		"said",
+		"--extended",
+		"--fixed-strings",
+		"--ignore-case",
+		"--patterns-file",
+		patternsFile,
+		// regex characters are matched literally
+		"skins on",
+	)
+	if err != nil {
+		t.Fatalf("failed to execute command: %v", err)
+	}
+
+	records := make([]string, 0, 8)
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n")[1:] {
+		fields := strings.Split(line, ",")
+		// nickname and pattern
+		records = append(records, fields[2]+":"+fields[len(fields)-1])
+	}
+
+	expected := "spammer:BUY GOLD,spammer:BUY GOLD,bot:T.ME/FREESKINS,bot6:T.ME/FREESKINS,spam:skins on,b0t:T.ME/FREESKINS"
+	if actual := strings.Join(records, ","); actual != expected {
+		t.Fatalf("expected %q, got %q", expected, actual)
+	}
+}
+
+func TestWhoSaidAfterShorthand(t *testing.T) {
+	ctx := context.TODO()
+
+	// -A is --include-archive and not --after like in grep, numbers are valid search patterns
+	_, err := testutils.Execute(NewRootCmd(ctx), "--search-dir", testutils.FilePath("testdata"), "--include-archive", "who", "said", "1337")
+	if err != nil {
+		t.Fatalf("failed to execute command: %v", err)
+	}
+
+	out, err := testutils.Execute(NewRootCmd(ctx), "--search-dir", testutils.FilePath("testdata"), "what", "said", "-A", "--after", "2", "-e", "spam")
+	if err != nil {
+		t.Fatalf("failed to execute command: %v", err)
+	}
+	if out.Len() == 0 {
+		t.Fatalf("expected some output, got nothing")
+	}
+}
+
+func TestWhoSaidContext(t *testing.T) {
+	ctx := context.TODO()
+
+	dir := t.TempDir()
+	content := "2024-05-01 18:01:12 I server: player has entered the game. ClientID=1 addr=<{5.6.7.8:41234}> sevendown=0\n" +
+		"2024-05-01 18:01:13 I chat: 1:-2:bot: join our telegram\n" +
+		"2024-05-01 18:01:14 I chat: 1:-2:bot: hello\n" +
+		"2024-05-01 18:01:15 I chat: 1:-2:bot: telegram again\n" +
+		"2024-05-01 18:01:16 I chat: 1:-2:bot: bye\n" +
+		"2024-05-01 18:01:17 I chat: 1:-2:bot: a\n" +
+		"2024-05-01 18:01:18 I chat: 1:-2:bot: b\n" +
+		"2024-05-01 18:01:19 I chat: 1:-2:bot: last telegram\n"
+	filePath := filepath.Join(dir, "server.log")
+	err := os.WriteFile(filePath, []byte(content), 0o644)
+	if err != nil {
+		t.Fatalf("failed to write log file: %v", err)
+	}
+
+	cmd := NewRootCmd(ctx)
+	out, err := testutils.Execute(
+		cmd,
+		"--search-dir",
+		dir,
+		"who",
+		"said",
+		"--extended",
+		"--context",
+		"1",
+		"telegram",
+	)
+	if err != nil {
+		t.Fatalf("failed to execute command: %v", err)
+	}
+
+	// overlapping windows are merged and groups are separated like grep does
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	expected := []string{
+		"1-2024-05-01 18:01:12 I server: player has entered the game. ClientID=1 addr=<{5.6.7.8:41234}> sevendown=0",
+		"2:2024-05-01 18:01:13 I chat: 1:-2:bot: join our telegram",
+		"3-2024-05-01 18:01:14 I chat: 1:-2:bot: hello",
+		"4:2024-05-01 18:01:15 I chat: 1:-2:bot: telegram again",
+		"5-2024-05-01 18:01:16 I chat: 1:-2:bot: bye",
+		"--",
+		"7-2024-05-01 18:01:18 I chat: 1:-2:bot: b",
+		"8:2024-05-01 18:01:19 I chat: 1:-2:bot: last telegram",
+	}
+	actual := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if !strings.HasPrefix(line, filePath) {
+			actual = append(actual, line)
+		}
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), out.String())
+	}
+
+	// streamed results print the same context lines
+	batch := strings.TrimSpace(out.String())
+	out, err = testutils.Execute(NewRootCmd(ctx), "--search-dir", dir, "--stream", "who", "said", "--extended", "--context", "1", "telegram")
+	if err != nil {
+		t.Fatalf("failed to execute command: %v", err)
+	}
+	if strings.TrimSpace(out.String()) != batch {
+		t.Fatalf("expected streamed output:\n%s\ngot:\n%s", batch, out.String())
+	}
+
+	// followed results are printed once no later match can share their context lines
+	followCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	cmd = NewRootCmd(followCtx)
+	follow := testutils.NewOutput()
+	cmd.SetOut(follow)
+	cmd.SetArgs([]string{"--search-dir", dir, "--follow", "--poll-interval", "10ms", "who", "said", "--extended", "--context", "1", "telegram"})
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Execute()
+	}()
+
+	f, err := os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("failed to open log file: %v", err)
+	}
+	_, err = f.WriteString(strings.Repeat("2024-05-01 18:01:20 I chat: 1:-2:bot: c\n", 4))
+	f.Close()
+	if err != nil {
+		t.Fatalf("failed to append to log file: %v", err)
+	}
+
+	out, err = testutils.Execute(NewRootCmd(ctx), "--search-dir", dir, "who", "said", "--extended", "--context", "1", "telegram")
+	if err != nil {
+		t.Fatalf("failed to execute command: %v", err)
+	}
+	expectedFollow := strings.TrimSpace(out.String())
+	if !follow.WaitFor(expectedFollow, 10*time.Second) {
+		t.Fatalf("expected followed output:\n%s\ngot:\n%s", expectedFollow, follow.String())
+	}
+	cancel()
+
+	err = <-done
+	if err != nil {
+		t.Fatalf("failed to execute command: %v", err)
+	}
+	if strings.TrimSpace(follow.String()) != expectedFollow {
+		t.Fatalf("expected followed output:\n%s\ngot:\n%s", expectedFollow, follow.String())
+	}
+
+	cmd = NewRootCmd(ctx)
+	out, err = testutils.Execute(
+		cmd,
+		"--search-dir",
+		dir,
+		"--output",
+		"json",
+		"who",
+		"said",
+		"--extended",
+		"--before",
+		"1",
+		"last telegram",
+	)
+	if err != nil {
+		t.Fatalf("failed to execute command: %v", err)
+	}
+
+	var players model.PlayerExtendedList
+	err = json.Unmarshal(out.Bytes(), &players)
+	if err != nil {
+		t.Fatalf("failed to unmarshal json: %v", err)
+	}
+	if len(players) != 1 || players[0].Context == nil {
+		t.Fatalf("expected one player with context lines, got %s", out.String())
+	}
+	if actual := players[0].Context.String(); actual != "7-"+strings.Split(content, "\n")[6]+"\n8:"+strings.Split(content, "\n")[7]+"\n" {
+		t.Fatalf("unexpected context lines: %q", actual)
+	}
+}
+
+func TestWhoSaidChannel(t *testing.T) {
+	ctx := context.TODO()
+
+	dir := t.TempDir()
+	content := "2024-05-01 18:01:12 I server: player has entered the game. ClientID=1 addr=<{5.6.7.8:41234}> sevendown=0\n" +
+		"2024-05-01 18:01:12 I server: player has entered the game. ClientID=2 addr=<{1.2.3.4:41234}> sevendown=0\n" +
+		"2024-05-01 18:01:13 I chat: 1:-2:bot: hello\n" +
+		"2024-05-01 18:01:14 I whisper: 1:2:bot: free skins t.me/x\n" +
+		"2024-05-01 18:01:15 I teamchat: 2:0:player: free skins? no\n" +
+		"2024-05-01 18:01:16 I chat: -1:-2:: no free skins here\n"
+	err := os.WriteFile(filepath.Join(dir, "server.log"), []byte(content), 0o644)
+	if err != nil {
+		t.Fatalf("failed to write log file: %v", err)
+	}
+
+	tests := []struct {
+		channel  string
+		expected string
+	}{
+		// server messages are only searched on demand
+		{"", "<{5.6.7.8}> bot: free skins t.me/x\n<{1.2.3.4}> player: free skins? no"},
+		{"whisper", "<{5.6.7.8}> bot: free skins t.me/x"},
+		{"team,server", "<{1.2.3.4}> player: free skins? no\n<{}> : no free skins here"},
+	}
+
+	for _, test := range tests {
+		args := []string{"--search-dir", dir, "who", "said", "free skins"}
+		if test.channel != "" {
+			args = append(args, "--channel", test.channel)
+		}
+
+		cmd := NewRootCmd(ctx)
+		out, err := testutils.Execute(cmd, args...)
+		if err != nil {
+			t.Fatalf("channel %q: failed to execute command: %v", test.channel, err)
+		}
+
+		if actual := strings.TrimSpace(out.String()); actual != test.expected {
+			t.Fatalf("channel %q: expected %q, got %q", test.channel, test.expected, actual)
+		}
+	}
+}
+
+func TestWhatSaidTrickyNickname(t *testing.T) {
+	ctx := context.TODO()
+
+	dir := t.TempDir()
+	content := "[5f1c2a3b][game]: id=0 addr=1.2.3.4:8303 version=1796 name='it's: me' clan='' country=-1\n" +
+		"[5f1c2a3b][chat]: 0:-2:it's: me: hi: all\n"
+	err := os.WriteFile(filepath.Join(dir, "server.log"), []byte(content), 0o644)
+	if err != nil {
+		t.Fatalf("failed to write log file: %v", err)
+	}
+
+	cmd := NewRootCmd(ctx)
+	out, err := testutils.Execute(
+		cmd,
+		"--search-dir",
+		dir,
+		"what",
+		"said",
+		"--fixed-strings",
+		"it's: me",
+	)
+	if err != nil {
+		t.Fatalf("failed to execute command: %v", err)
+	}
+
+	expected := "<{1.2.3.4}> it's: me: hi: all"
+	if actual := strings.TrimSpace(out.String()); actual != expected {
+		t.Fatalf("expected %q, got %q", expected, actual)
+	}
+}
+
+func TestWhoSaidDialectFile(t *testing.T) {
+	ctx := context.TODO()
+
+	dir := t.TempDir()
+	logDir := filepath.Join(dir, "logs")
+	err := os.Mkdir(logDir, 0o755)
+	if err != nil {
+		t.Fatalf("failed to create log dir: %v", err)
+	}
+
+	files := map[string]string{
+		"logs/fork.log": "2024-05-01 18:01:12 [fork] join 1 5.6.7.8:8303 'bot'\n" +
+			"2024-05-01 18:01:13 [fork] bot (1): join our telegram\n",
+		"logs/ddnet.log": "2024-05-01 18:01:12 I server: player has entered the game. ClientID=1 addr=<{1.2.3.4:41234}> sevendown=0\n" +
+			"2024-05-01 18:01:13 I chat: 1:-2:spam: telegram too\n",
+		"dialects.yaml": "dialects:\n" +
+			"  - name: fork\n" +
+			"    join: '\\[fork\\] join (?P<id>\\d+) (?P<ip>\\S+) ''(?P<name>.*)'''\n" +
+			"    chat: '\\[fork\\] (?P<name>.+) \\((?P<id>\\d+)\\): (?P<text>.+)'\n",
+		"dialects.toml": "[[dialects]]\n" +
+			"name = 'fork'\n" +
+			"join = '''\\[fork\\] join (?P<id>\\d+) (?P<ip>\\S+) '(?P<name>.*)''''\n" +
+			"chat = '''\\[fork\\] (?P<name>.+) \\((?P<id>\\d+)\\): (?P<text>.+)'''\n",
+	}
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)
+		if err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		dialectFile string
+		dialect     string
+		expected    string
+	}{
+		// the dialect of each file is detected automatically
+		{"dialects.yaml", "auto", "<{1.2.3.4}> spam: telegram too\n<{5.6.7.8}> bot: join our telegram"},
+		{"dialects.toml", "auto", "<{1.2.3.4}> spam: telegram too\n<{5.6.7.8}> bot: join our telegram"},
+		{"dialects.yaml", "builtin", "<{1.2.3.4}> spam: telegram too"},
+		// lines of other dialects are still parsed by the built-in parser
+		{"dialects.toml", "fork", "<{1.2.3.4}> spam: telegram too\n<{5.6.7.8}> bot: join our telegram"},
+	}
+
+	for _, test := range tests {
+		cmd := NewRootCmd(ctx)
+		out, err := testutils.Execute(
+			cmd,
+			"--search-dir",
+			logDir,
+			"--dialect-file",
+			filepath.Join(dir, test.dialectFile),
+			"--dialect-name",
+			test.dialect,
+			"who",
+			"said",
+			"telegram",
+		)
+		if err != nil {
+			t.Fatalf("%s %s: failed to execute command: %v", test.dialectFile, test.dialect, err)
+		}
+
+		if actual := strings.TrimSpace(out.String()); actual != test.expected {
+			t.Fatalf("%s %s: expected %q, got %q", test.dialectFile, test.dialect, test.expected, actual)
+		}
+	}
+}
+
+func TestIndexQuery(t *testing.T) {
+	ctx := context.TODO()
+
+	dir := t.TempDir()
+	logDir := filepath.Join(dir, "logs")
+	err := os.Mkdir(logDir, 0o755)
+	if err != nil {
+		t.Fatalf("failed to create log dir: %v", err)
+	}
+
+	var (
+		logFile   = filepath.Join(logDir, "ddnet.log")
+		archive   = filepath.Join(logDir, "old.zip")
+		indexFile = filepath.Join(dir, "twlog.index")
+		join      = "2024-05-01 18:01:12 I server: player has entered the game. ClientID=1 addr=<{1.2.3.4:41234}> sevendown=0\n"
+	)
+
+	// DDNet announces the nickname after the join line, which is resolved when the index is built
+	content := join +
+		"2024-05-01 18:01:12 I chat: *** 'spam' entered and joined the game\n" +
+		"2024-05-01 18:01:12 I game: kill killer='1:spam' victim='1:spam' weapon=-1 special=0\n" +
+		"2024-05-01 18:01:13 I chat: 1:-2:spam: telegram one\n" +
+		"2024-05-01 18:01:14 I chat: *** 'spam' changed name to 'renamed'\n"
+	err = os.WriteFile(logFile, []byte(content), 0o644)
+	if err != nil {
+		t.Fatalf("failed to write log file: %v", err)
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("old.log")
+	if err != nil {
+		t.Fatalf("failed to create archive entry: %v", err)
+	}
+	_, err = io.WriteString(w, "2024-04-01 18:01:12 I server: player has entered the game. ClientID=2 addr=<{5.6.7.8:41234}> sevendown=0\n"+
+		"2024-04-01 18:01:13 I chat: 2:-2:bot: join our telegram\n")
+	if err != nil {
+		t.Fatalf("failed to write archive entry: %v", err)
+	}
+	err = zw.Close()
+	if err != nil {
+		t.Fatalf("failed to close archive: %v", err)
+	}
+	err = os.WriteFile(archive, buf.Bytes(), 0o644)
+	if err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+
+	execute := func(args ...string) string {
+		t.Helper()
+		args = append([]string{
+			"--concurrency", "1",
+			"--search-dir", logDir,
+			"--include-archive",
+			"--index-file", indexFile,
+		}, args...)
+		out, err := testutils.Execute(NewRootCmd(ctx), args...)
+		if err != nil {
+			t.Fatalf("failed to execute %v: %v", args, err)
+		}
+		return strings.TrimSpace(out.String())
+	}
+
+	// the index does not exist yet
+	_, err = testutils.Execute(NewRootCmd(ctx), "--search-dir", logDir, "--index-file", indexFile, "index", "query", "who", "said", "telegram")
+	if err == nil {
+		t.Fatalf("expected an error without index")
+	}
+
+	execute("index", "build")
+
+	expected := "<{1.2.3.4}> spam: telegram one\n<{5.6.7.8}> bot: join our telegram"
+	if actual := execute("who", "said", "telegram"); actual != expected {
+		t.Fatalf("who said: expected %q, got %q", expected, actual)
+	}
+	if actual := execute("index", "query", "who", "said", "telegram"); actual != expected {
+		t.Fatalf("index query who said: expected %q, got %q", expected, actual)
+	}
+	// the index behaves like the log files with the same flags
+	for _, args := range [][]string{
+		{"what", "said", "spam"},
+		{"who", "said", "-e", "telegram"},
+		{"--output", "json", "what", "said", "-e", "spam"},
+	} {
+		direct := execute(args...)
+		indexed := execute(slices.Insert(args, slices.Index(args, "said")-1, "index", "query")...)
+		if indexed != direct || indexed == "" {
+			t.Fatalf("index query %v: expected %q, got %q", args, direct, indexed)
+		}
+	}
+
+	// the lines around a match are not indexed
+	_, err = testutils.Execute(NewRootCmd(ctx), "--search-dir", logDir, "--index-file", indexFile, "index", "query", "who", "said", "-e", "-C", "2", "telegram")
+	if err == nil || !strings.Contains(err.Error(), "--context") {
+		t.Fatalf("expected context lines to be rejected, got %v", err)
+	}
+
+	// files with the same modification time and size are not read again
+	info, err := os.Stat(logFile)
+	if err != nil {
+		t.Fatalf("failed to stat log file: %v", err)
+	}
+	err = os.WriteFile(logFile, []byte(strings.Replace(content, "telegram one", "telegram two", 1)), 0o644)
+	if err != nil {
+		t.Fatalf("failed to write log file: %v", err)
+	}
+	err = os.Chtimes(logFile, info.ModTime(), info.ModTime())
+	if err != nil {
+		t.Fatalf("failed to restore modification time: %v", err)
+	}
+	if actual := execute("index", "query", "who", "said", "telegram"); actual != expected {
+		t.Fatalf("index query of unchanged file: expected %q, got %q", expected, actual)
+	}
+
+	// changed files are read instead of the index until the index is rebuilt
+	err = os.WriteFile(logFile, []byte(join+"2024-05-01 18:01:13 I chat: 1:-2:spam: telegram three\n"), 0o644)
+	if err != nil {
+		t.Fatalf("failed to write log file: %v", err)
+	}
+	err = os.Chtimes(logFile, info.ModTime().Add(time.Minute), info.ModTime().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("failed to change modification time: %v", err)
+	}
+
+	expected = "<{1.2.3.4}> spam: telegram three\n<{5.6.7.8}> bot: join our telegram"
+	if actual := execute("index", "query", "who", "said", "telegram"); actual != expected {
+		t.Fatalf("index query of changed file: expected %q, got %q", expected, actual)
+	}
+
+	// deleted archives are pruned from the index
+	err = os.Remove(archive)
+	if err != nil {
+		t.Fatalf("failed to remove archive: %v", err)
+	}
+	execute("index", "build", "--prune")
+
+	if actual := execute("index", "query", "who", "said", "telegram"); actual != "<{1.2.3.4}> spam: telegram three" {
+		t.Fatalf("index query after rebuild: expected %q, got %q", "<{1.2.3.4}> spam: telegram three", actual)
+	}
+}
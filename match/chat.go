@@ -6,22 +6,106 @@ import (
 )
 
 var (
-	// id, nick, chat line
-	chatLineRegexp = regexp.MustCompile(`chat: (\d+):-?\d+:(.+): (.+)`)
+	// 0: full 1: category 2: id 3: team or whisper target, followed by nick: chat line
+	// the rest of the line is split by splitChat, because nicknames and messages may both contain ": "
+	chatLineRegexp = regexp.MustCompile(`(teamchat|whisper|chat)\]?: (-?\d+):(-?\d+):`)
 )
 
+// Channel is the chat channel a message was sent to.
+type Channel string
+
+const (
+	// ChannelAll contains the messages that every player can read.
+	ChannelAll Channel = "all"
+	// ChannelTeam contains the messages that only the team (or the spectators) of the player can read.
+	ChannelTeam Channel = "team"
+	// ChannelWhisper contains private messages to a single player.
+	ChannelWhisper Channel = "whisper"
+	// ChannelServer contains the messages of the server itself, e.g. the rcon say command.
+	ChannelServer Channel = "server"
+)
+
+// Channels contains all known chat channels.
+var Channels = []Channel{ChannelAll, ChannelTeam, ChannelWhisper, ChannelServer}
+
+// teamAll is the team field of messages to all players.
+const teamAll = -2
+
+// ChatDetails contains everything that a chat line contains.
+// Team is the team field of the line, Target is the client id of the receiver
+// of a whisper and -1 otherwise. Messages of the server have the ID -1.
+type ChatDetails struct {
+	ID      int
+	Channel Channel
+	Team    int
+	Target  int
+	Name    string
+	Text    string
+}
+
 func Chat(line string) (id int, nick string, chat string, ok bool) {
-	matches := chatLineRegexp.FindStringSubmatch(line)
-	if len(matches) == 0 {
+	d, ok := ChatDetailed(line)
+	if !ok {
 		return -1, "", "", false
 	}
+	return d.ID, d.Name, d.Text, true
+}
 
-	id, err := strconv.Atoi(matches[1])
+// ChatDetailed returns all information that a chat, team chat or whisper line contains.
+func ChatDetailed(line string) (d ChatDetails, ok bool) {
+	return ChatDetailedWithNames(line, nil)
+}
+
+// ChatDetailedWithNames is like ChatDetailed, but prefers the known nickname of the client
+// in case the line could be split into nickname and message in several ways.
+func ChatDetailedWithNames(line string, names Names) (d ChatDetails, ok bool) {
+	loc := chatLineRegexp.FindStringSubmatchIndex(line)
+	if loc == nil {
+		return ChatDetails{ID: -1, Target: -1}, false
+	}
+	var (
+		category = line[loc[2]:loc[3]]
+		rest     = line[loc[1]:]
+	)
+
+	// the regex only matches digits, but they may still overflow an int
+	id, err := strconv.Atoi(line[loc[4]:loc[5]])
+	if err != nil {
+		return ChatDetails{ID: -1, Target: -1}, false
+	}
+	field, err := strconv.Atoi(line[loc[6]:loc[7]])
 	if err != nil {
-		// must match, otherwise hte regex is wrong
-		panic(err)
+		return ChatDetails{ID: -1, Target: -1}, false
+	}
+
+	knownName := ""
+	if names != nil && id >= 0 {
+		knownName, _ = names(id)
+	}
+	name, text, ok := splitChat(rest, knownName, id < 0)
+	if !ok {
+		return ChatDetails{ID: -1, Target: -1}, false
+	}
+
+	d = ChatDetails{
+		ID:     id,
+		Team:   field,
+		Target: -1,
+		Name:   name,
+		Text:   text,
+	}
+
+	switch {
+	case id < 0:
+		d.Channel = ChannelServer
+	case category == "whisper":
+		d.Channel = ChannelWhisper
+		d.Team = teamAll
+		d.Target = field
+	case category == "teamchat" || field != teamAll:
+		d.Channel = ChannelTeam
+	default:
+		d.Channel = ChannelAll
 	}
-	nick = matches[2]
-	chat = matches[3]
-	return id, nick, chat, true
+	return d, true
 }
@@ -0,0 +1,28 @@
+package match
+
+import "testing"
+
+func TestChatDetailed(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected ChatDetails
+		ok       bool
+	}{
+		{"2024-05-01 18:00:10 I chat: 0:-2:OPlayer: hi all", ChatDetails{0, ChannelAll, -2, -1, "OPlayer", "hi all"}, true},
+		{"2024-05-01 18:00:10 I chat: 1:0:red player: go left", ChatDetails{1, ChannelTeam, 0, -1, "red player", "go left"}, true},
+		{"2024-05-01 18:00:10 I teamchat: 2:1:blue: defend", ChatDetails{2, ChannelTeam, 1, -1, "blue", "defend"}, true},
+		{"2024-05-01 18:00:10 I whisper: 3:7:bot: free skins t.me/x", ChatDetails{3, ChannelWhisper, -2, 7, "bot", "free skins t.me/x"}, true},
+		{"2024-05-01 18:00:10 I chat: -1:-2:: server restarts in 5 minutes", ChatDetails{-1, ChannelServer, -2, -1, "", "server restarts in 5 minutes"}, true},
+		{"2024-05-01 18:00:10 I chat: 4:-2:: no name", ChatDetails{-1, "", 0, -1, "", ""}, false},
+		{"2024-05-01 18:00:10 I chat: 99999999999999999999:-2:OPlayer: hi all", ChatDetails{-1, "", 0, -1, "", ""}, false},
+		{"2024-05-01 18:00:10 I whisper: 3:99999999999999999999:bot: hi", ChatDetails{-1, "", 0, -1, "", ""}, false},
+		{"2024-05-01 18:00:04 I chat: *** 'nameless tee' changed name to 'OPlayer'", ChatDetails{-1, "", 0, -1, "", ""}, false},
+	}
+
+	for _, tt := range tests {
+		d, ok := ChatDetailed(tt.line)
+		if d != tt.expected || ok != tt.ok {
+			t.Errorf("ChatDetailed(%q) = (%+v, %v), want (%+v, %v)", tt.line, d, ok, tt.expected, tt.ok)
+		}
+	}
+}
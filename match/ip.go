@@ -0,0 +1,41 @@
+package match
+
+import (
+	"net/netip"
+	"strings"
+)
+
+const (
+	// addrPattern captures an IPv4 or IPv6 address with an optional port.
+	// IPv6 addresses with a port must be enclosed in brackets.
+	// DDNet additionally wraps addresses in <{...}>.
+	addrPattern = `<?\{?(\[[a-fA-F0-9:.%]+\](?::\d+)?|[a-fA-F0-9:.]+)\}?>?`
+)
+
+// NormalizeIP parses an IPv4 or IPv6 address with or without brackets and port
+// and returns its canonical string representation without port.
+// IPv4-mapped IPv6 addresses are converted to plain IPv4 addresses.
+func NormalizeIP(addr string) (ip string, ok bool) {
+	ip, _, ok = SplitAddr(addr)
+	return ip, ok
+}
+
+// SplitAddr splits an address into its normalized IP and its port.
+// The port is 0 in case the address does not contain one.
+func SplitAddr(addr string) (ip string, port int, ok bool) {
+	addr = strings.TrimSpace(addr)
+	addr = strings.TrimPrefix(addr, "<{")
+	addr = strings.TrimSuffix(addr, "}>")
+
+	var a netip.Addr
+	if ap, err := netip.ParseAddrPort(addr); err == nil {
+		a = ap.Addr()
+		port = int(ap.Port())
+	} else if pa, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")); err == nil {
+		a = pa
+	} else {
+		return "", 0, false
+	}
+
+	return a.Unmap().WithZone("").String(), port, true
+}
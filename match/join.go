@@ -6,38 +6,155 @@ import (
 )
 
 var (
-	// 0: full 1: ID 2: IP
-	ddnetJoinRegex = regexp.MustCompile(`(?i)player has entered the game\. ClientID=([\d]+) addr=[^\d]{0,2}([\d]{1,3}\.[\d]{1,3}\.[\d]{1,3}\.[\d]{1,3})[^\d]{0,2}`)
+	// 0: full 1: ID 2: IP with optional port
+	ddnetJoinRegex = regexp.MustCompile(`(?i)player has entered the game\. ClientID=([\d]+) addr=` + addrPattern)
 
-	// 0: full 1: ID 2: IP 3: port 4: version 5: name 6: clan 7: country
-	playerzCatchJoinRegex = regexp.MustCompile(`(?i)id=([\d]+) addr=([a-fA-F0-9\.\:\[\]]+):([\d]+) version=(\d+) name='(.{0,20})' clan='(.{0,16})' country=([-\d]+)$`)
+	// 0: full 1: ID 2: IP 3: port 4: version 5: name' clan='clan 6: country
+	// the name and clan are split by splitNameClan, because both may contain quotes
+	playerzCatchJoinRegex = regexp.MustCompile(`(?i)id=([\d]+) addr=([a-fA-F0-9\.\:\[\]]+):([\d]+) version=(\d+) name='(.*)' country=([-\d]+)$`)
 
-	// 0: full 1: ID 2: IP
-	playerVanillaJoinRegex = regexp.MustCompile(`(?i)player is ready\. ClientID=([\d]+) addr=[^\d]{0,2}([\d]{1,3}\.[\d]{1,3}\.[\d]{1,3}\.[\d]{1,3})[^\d]{0,2}`)
+	// 0: full 1: ID 2: IP with optional port
+	playerVanillaJoinRegex = regexp.MustCompile(`(?i)player is ready\. ClientID=([\d]+) addr=` + addrPattern)
+
+	// 0: full 1: name
+	enterRegex = regexp.MustCompile(`chat\]?: \*\*\* '(.*)' entered and joined the (?:game|spectators)`)
+
+	// 0: full 1: ID 2: name 3: team
+	teamJoinRegex = regexp.MustCompile(`team_join player='(\d+):(.*)' (?:m_T|t)eam=(-?\d+)`)
+
+	// 0: full 1: ID 2: version
+	clientVersionRegex = regexp.MustCompile(`(?i)ddnet: cid=(\d+) version=(\d+)`)
 )
 
+// JoinDetails contains everything that is known about a player that joined the server.
+// Depending on the log dialect some fields are not available: Port and Version are 0,
+// Name and Clan are empty and Country is -1 in that case.
+type JoinDetails struct {
+	ID      int
+	IP      string
+	Port    int
+	Version int
+	Name    string
+	Clan    string
+	Country int
+}
+
+// Join returns the client id and the normalized IP address of a player that joined the server.
 func Join(line string) (id int, ip string, ok bool) {
+	d, ok := JoinDetailed(line)
+	if !ok {
+		return -1, "", false
+	}
+	return d.ID, d.IP, true
+}
+
+// JoinDetailed returns all information that a join line contains.
+func JoinDetailed(line string) (d JoinDetails, ok bool) {
+	d = JoinDetails{
+		ID:      -1,
+		Country: -1,
+	}
+
 	var (
 		joinIDStr string
-		joinIP    string
+		joinAddr  string
 	)
 	if matches := ddnetJoinRegex.FindStringSubmatch(line); len(matches) != 0 {
 		joinIDStr = matches[1]
-		joinIP = matches[2]
+		joinAddr = matches[2]
 	} else if matches := playerzCatchJoinRegex.FindStringSubmatch(line); len(matches) != 0 {
 		joinIDStr = matches[1]
-		joinIP = matches[2]
+		joinAddr = matches[2]
+
+		port, err := strconv.Atoi(matches[3])
+		if err != nil {
+			return JoinDetails{}, false
+		}
+		version, err := strconv.Atoi(matches[4])
+		if err != nil {
+			return JoinDetails{}, false
+		}
+		name, clan, ok := splitNameClan(matches[5])
+		if !ok {
+			return JoinDetails{}, false
+		}
+		country, err := strconv.Atoi(matches[6])
+		if err != nil {
+			return JoinDetails{}, false
+		}
+		d.Port = port
+		d.Version = version
+		d.Name = name
+		d.Clan = clan
+		d.Country = country
 	} else if matches := playerVanillaJoinRegex.FindStringSubmatch(line); len(matches) != 0 {
 		joinIDStr = matches[1]
-		joinIP = matches[2]
+		joinAddr = matches[2]
 	} else {
-		return -1, "", false
+		return JoinDetails{}, false
 	}
 
 	joinId, err := strconv.Atoi(joinIDStr)
 	if err != nil {
-		return -1, "", false
+		return JoinDetails{}, false
+	}
+	d.ID = joinId
+
+	ip, port, ok := SplitAddr(joinAddr)
+	if !ok {
+		return JoinDetails{}, false
+	}
+	d.IP = ip
+	if port != 0 {
+		d.Port = port
+	}
+
+	return d, true
+}
+
+// Enter returns the name of a player that entered the game.
+// DDNet announces the name in the chat right after the join line.
+func Enter(line string) (name string, ok bool) {
+	matches := enterRegex.FindStringSubmatch(line)
+	if len(matches) == 0 {
+		return "", false
+	}
+	return matches[1], true
+}
+
+// TeamJoin returns the client id, the name and the new team of a player that joined a team.
+// Unlike the join line of DDNet and vanilla servers, it connects the client id with the name of the player.
+func TeamJoin(line string) (id int, name string, team int, ok bool) {
+	matches := teamJoinRegex.FindStringSubmatch(line)
+	if len(matches) == 0 {
+		return -1, "", 0, false
+	}
+
+	id, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return -1, "", 0, false
+	}
+	team, err = strconv.Atoi(matches[3])
+	if err != nil {
+		return -1, "", 0, false
+	}
+	return id, matches[2], team, true
+}
+
+// ClientVersion returns the client id and the DDNet client version a player connected with.
+func ClientVersion(line string) (id int, version int, ok bool) {
+	matches := clientVersionRegex.FindStringSubmatch(line)
+	if len(matches) == 0 {
+		return -1, 0, false
 	}
 
-	return joinId, joinIP, true
+	id, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return -1, 0, false
+	}
+	version, err = strconv.Atoi(matches[2])
+	if err != nil {
+		return -1, 0, false
+	}
+	return id, version, true
 }
@@ -0,0 +1,29 @@
+package match
+
+import "testing"
+
+func TestJoin(t *testing.T) {
+	tests := []struct {
+		line string
+		id   int
+		ip   string
+		ok   bool
+	}{
+		{"2024-05-01 18:00:03 I server: player has entered the game. ClientID=0 addr=<{1.2.3.4:53212}> sevendown=0", 0, "1.2.3.4", true},
+		{"2024-05-01 18:00:03 I server: player has entered the game. ClientID=1 addr=<{[2001:db8::1]:53212}> sevendown=0", 1, "2001:db8::1", true},
+		{"2024-05-01 18:00:03 I server: player has entered the game. ClientID=2 addr=<{[2001:0db8:0000::0001]:53212}> sevendown=0", 2, "2001:db8::1", true},
+		{"[5f1c2a3b][server]: player is ready. ClientID=3 addr=10.0.0.1:8303", 3, "10.0.0.1", true},
+		{"[5f1c2a3b][server]: player is ready. ClientID=4 addr=[::ffff:10.0.0.1]:8303", 4, "10.0.0.1", true},
+		{"[5f1c2a3b][server]: player is ready. ClientID=5 addr=2001:db8::2", 5, "2001:db8::2", true},
+		{"[5f1c2a3b][game]: id=6 addr=[2001:db8::3]:8303 version=1796 name='name' clan='clan' country=-1", 6, "2001:db8::3", true},
+		{"[5f1c2a3b][game]: id=7 addr=2001:db8::4:8303 version=1796 name='name' clan='clan' country=-1", 7, "2001:db8::4", true},
+		{"[5f1c2a3b][server]: player is ready. ClientID=8 addr=not-an-ip", -1, "", false},
+	}
+
+	for _, tt := range tests {
+		id, ip, ok := Join(tt.line)
+		if id != tt.id || ip != tt.ip || ok != tt.ok {
+			t.Errorf("Join(%q) = (%d, %q, %v), want (%d, %q, %v)", tt.line, id, ip, ok, tt.id, tt.ip, tt.ok)
+		}
+	}
+}
@@ -7,20 +7,26 @@ import (
 
 var (
 	// 0: full 1: ID 2: IP 3: reason
-	playerLeftRegex = regexp.MustCompile(`id=([\d]+) addr=([a-fA-F0-9\.\:\[\]]+) reason='(.*)'$`)
+	playerLeftRegex = regexp.MustCompile(`id=([\d]+) addr=` + addrPattern + ` reason='(.*)'$`)
 )
 
-func Leave(line string) (id int, ok bool) {
+// Leave returns the client id, the normalized IP address and the reason of a player that left the server.
+func Leave(line string) (id int, ip string, reason string, ok bool) {
 	matches := playerLeftRegex.FindStringSubmatch(line)
 	if len(matches) == 0 {
-		return -1, false
+		return -1, "", "", false
 	}
 
 	idStr := matches[1]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		return -1, false
+		return -1, "", "", false
 	}
 
-	return id, true
+	ip, ok = NormalizeIP(matches[2])
+	if !ok {
+		return -1, "", "", false
+	}
+
+	return id, ip, matches[3], true
 }
@@ -0,0 +1,17 @@
+package match
+
+import "regexp"
+
+var (
+	// 0: full 1: map name
+	// DDNet logs the sha256 checksum of a loaded map, vanilla its crc
+	mapChangeRegex = regexp.MustCompile(`(?i)\bmaps/(.+)\.map (?:sha256|crc) is `)
+)
+
+func MapChange(line string) (mapName string, ok bool) {
+	matches := mapChangeRegex.FindStringSubmatch(line)
+	if len(matches) == 0 {
+		return "", false
+	}
+	return matches[1], true
+}
@@ -0,0 +1,95 @@
+package match
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// 0: full 1: IP or IP range 2: minutes (empty for permanent bans) 3: reason
+	banRegex = regexp.MustCompile(`(?i)net_ban: banned '([^']+)' for (?:(\d+) minutes?|life) \((.*)\)$`)
+
+	// 0: full 1: IP or IP range 2: IP or IP range of bans that were removed by index
+	unbanRegex = regexp.MustCompile(`(?i)net_ban: unbanned (?:'([^']+)'|index \d+ \((.+)\))`)
+
+	// 0: full 1: ID 2: command
+	rconRegex = regexp.MustCompile(`(?i)ClientID=(\d+) rcon='(.*)'$`)
+
+	// 0: full 1: caller 2: target 3: reason
+	voteKickRegex = regexp.MustCompile(`chat\]?: \*\*\* '(.*)' called (?:for )?vote to kick '(.*)' \((.*)\)$`)
+
+	// leave reasons of players that were kicked from the server
+	kickReasonRegex = regexp.MustCompile(`(?i)^kicked\b|\bkicked by\b`)
+)
+
+// Ban returns the banned IP address or IP range, the ban duration and the ban reason.
+// Permanent bans have a duration of zero.
+func Ban(line string) (target string, d time.Duration, permanent bool, reason string, ok bool) {
+	matches := banRegex.FindStringSubmatch(line)
+	if len(matches) == 0 {
+		return "", 0, false, "", false
+	}
+
+	target = normalizeBanTarget(matches[1])
+	if matches[2] == "" {
+		return target, 0, true, matches[3], true
+	}
+
+	minutes, err := strconv.Atoi(matches[2])
+	if err != nil {
+		return "", 0, false, "", false
+	}
+	return target, time.Duration(minutes) * time.Minute, false, matches[3], true
+}
+
+// Unban returns the IP address or IP range that was unbanned.
+func Unban(line string) (target string, ok bool) {
+	matches := unbanRegex.FindStringSubmatch(line)
+	if len(matches) == 0 {
+		return "", false
+	}
+	target = matches[1]
+	if target == "" {
+		target = matches[2]
+	}
+	return normalizeBanTarget(target), true
+}
+
+// Rcon returns the client id of an authenticated player and the remote console command they executed.
+func Rcon(line string) (id int, command string, ok bool) {
+	matches := rconRegex.FindStringSubmatch(line)
+	if len(matches) == 0 {
+		return -1, "", false
+	}
+
+	id, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return -1, "", false
+	}
+	return id, matches[2], true
+}
+
+// VoteKick returns the nickname of the player that called a vote to kick the target player and the reason of the vote.
+func VoteKick(line string) (caller, target, reason string, ok bool) {
+	matches := voteKickRegex.FindStringSubmatch(line)
+	if len(matches) == 0 {
+		return "", "", "", false
+	}
+	return matches[1], matches[2], matches[3], true
+}
+
+// KickReason reports whether a leave reason was caused by a kick.
+func KickReason(reason string) bool {
+	return kickReasonRegex.MatchString(reason)
+}
+
+// normalizeBanTarget normalizes single IP addresses and leaves IP ranges like '1.2.3.4 - 1.2.3.10' as they are.
+func normalizeBanTarget(target string) string {
+	target = strings.TrimSpace(target)
+	if ip, ok := NormalizeIP(target); ok {
+		return ip
+	}
+	return target
+}
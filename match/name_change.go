@@ -0,0 +1,16 @@
+package match
+
+import "regexp"
+
+var (
+	// 0: full 1: old name 2: new name
+	nameChangeRegex = regexp.MustCompile(`chat\]?: \*\*\* '(.*)' changed name to '(.*)'$`)
+)
+
+func NameChange(line string) (oldName, newName string, ok bool) {
+	matches := nameChangeRegex.FindStringSubmatch(line)
+	if len(matches) == 0 {
+		return "", "", false
+	}
+	return matches[1], matches[2], true
+}
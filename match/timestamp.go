@@ -0,0 +1,42 @@
+package match
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+)
+
+const (
+	// TimestampLayout is the layout DDNet uses for the timestamp at the start of each log line.
+	TimestampLayout = "2006-01-02 15:04:05"
+)
+
+var (
+	// 0: full 1: date and time
+	// [2024-01-02 15:04:05][chat]: ... or 2024-01-02 15:04:05 I chat: ...
+	ddnetTimestampRegex = regexp.MustCompile(`^\[?(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}(?:\.\d+)?)\]?`)
+
+	// 0: full 1: unix timestamp in hex
+	// [5f1c2a3b][chat]: ...
+	vanillaTimestampRegex = regexp.MustCompile(`^\[([a-fA-F0-9]{8,16})\]`)
+)
+
+// Timestamp extracts the time at which a log line was written.
+// DDNet lines contain a local date and time, vanilla lines a hexadecimal unix timestamp.
+func Timestamp(line string) (t time.Time, ok bool) {
+	if matches := ddnetTimestampRegex.FindStringSubmatch(line); len(matches) != 0 {
+		t, err := time.ParseInLocation(TimestampLayout, matches[1], time.Local)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return t, true
+	} else if matches := vanillaTimestampRegex.FindStringSubmatch(line); len(matches) != 0 {
+		unix, err := strconv.ParseInt(matches[1], 16, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return time.Unix(unix, 0), true
+	}
+
+	return time.Time{}, false
+}
@@ -0,0 +1,30 @@
+package match
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimestamp(t *testing.T) {
+	tests := []struct {
+		line string
+		want time.Time
+		ok   bool
+	}{
+		{"[2024-01-02 15:04:05][chat]: 0:-2:name: text", time.Date(2024, 1, 2, 15, 4, 5, 0, time.Local), true},
+		{"2024-01-02 15:04:05 I chat: 0:-2:name: text", time.Date(2024, 1, 2, 15, 4, 5, 0, time.Local), true},
+		{"[5f1c2a3b][chat]: 0:0:name: text", time.Unix(0x5f1c2a3b, 0), true},
+		{"chat: 0:0:name: text", time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := Timestamp(tt.line)
+		if ok != tt.ok {
+			t.Errorf("Timestamp(%q) ok = %v, want %v", tt.line, ok, tt.ok)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("Timestamp(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package match
+
+import "strings"
+
+const (
+	// MaxNameLength is the maximum number of bytes of a nickname, the server truncates longer names.
+	MaxNameLength = 15
+	// MaxClanLength is the maximum number of bytes of a clan name.
+	MaxClanLength = 11
+)
+
+// Names returns the nickname of a client id in case it is already known, e.g. from its join line.
+type Names func(id int) (name string, ok bool)
+
+// separators returns the byte offsets of all occurrences of sep in s.
+func separators(s, sep string) []int {
+	offsets := make([]int, 0, 2)
+	for i := 0; ; {
+		idx := strings.Index(s[i:], sep)
+		if idx < 0 {
+			return offsets
+		}
+		offsets = append(offsets, i+idx)
+		i += idx + 1
+	}
+}
+
+// splitChat splits the part of a chat line after the team field into the nickname and the message.
+// Both may contain the separator ": ", which is why a known nickname of the client is preferred.
+// Otherwise the first split with a valid nickname is chosen, as messages contain ": " more often than names do.
+func splitChat(s, knownName string, server bool) (name, text string, ok bool) {
+	if knownName != "" && strings.HasPrefix(s, knownName+": ") && len(s) > len(knownName)+2 {
+		return knownName, s[len(knownName)+2:], true
+	}
+
+	candidates := make([]int, 0, 2)
+	for _, idx := range separators(s, ": ") {
+		if idx+2 == len(s) {
+			// messages are never empty
+			continue
+		}
+		candidates = append(candidates, idx)
+	}
+	if len(candidates) == 0 {
+		return "", "", false
+	}
+
+	if server {
+		// the server has no name
+		if candidates[0] != 0 {
+			return "", "", false
+		}
+		return "", s[2:], true
+	}
+
+	best := -1
+	for _, idx := range candidates {
+		if idx == 0 {
+			continue
+		}
+		if idx <= MaxNameLength {
+			best = idx
+			break
+		}
+		if best < 0 {
+			// forks may allow longer names
+			best = idx
+		}
+	}
+	if best < 0 {
+		return "", "", false
+	}
+	return s[:best], s[best+2:], true
+}
+
+// splitNameClan splits the quoted name and clan values of a zCatch join line,
+// which is name' clan='clan without the outer quotes.
+// Names and clans may contain quotes themselves, which is why the byte limits decide between several splits.
+func splitNameClan(s string) (name, clan string, ok bool) {
+	const sep = "' clan='"
+
+	candidates := separators(s, sep)
+	if len(candidates) == 0 {
+		return "", "", false
+	}
+
+	for _, idx := range candidates {
+		name, clan = s[:idx], s[idx+len(sep):]
+		if len(name) <= MaxNameLength && len(clan) <= MaxClanLength {
+			return name, clan, true
+		}
+	}
+
+	// forks may allow longer names, clans are shorter than names
+	idx := candidates[len(candidates)-1]
+	return s[:idx], s[idx+len(sep):], true
+}
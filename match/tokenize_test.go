@@ -0,0 +1,80 @@
+package match
+
+import "testing"
+
+func TestChatTokenizer(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      string
+		knownName string
+		nick      string
+		text      string
+		ok        bool
+	}{
+		{"plain", "2024-05-01 18:00:10 I chat: 0:-2:OPlayer: hi all", "", "OPlayer", "hi all", true},
+		{"vanilla brackets", "[66326cf0][chat]: 0:-2:OPlayer: hi all", "", "OPlayer", "hi all", true},
+		{"colon in message", "2024-05-01 18:00:10 I chat: 0:-2:bot: join: discord.gg/x", "", "bot", "join: discord.gg/x", true},
+		{"url in message", "2024-05-01 18:00:10 I chat: 0:-2:bot: https://bot.xyz/a: b", "", "bot", "https://bot.xyz/a: b", true},
+		{"colon in name", "2024-05-01 18:00:10 I chat: 0:-2:a: b: hello", "a: b", "a: b", "hello", true},
+		{"colon in name and message", "2024-05-01 18:00:10 I chat: 0:-2:x: y: note: gg", "x: y", "x: y", "note: gg", true},
+		{"stale known name", "2024-05-01 18:00:10 I chat: 0:-2:other: hi", "bot", "other", "hi", true},
+		{"colon at end of name", "2024-05-01 18:00:10 I chat: 0:-2:nick:: hi", "", "nick:", "hi", true},
+		{"quotes in name", "2024-05-01 18:00:10 I chat: 0:-2:'quoted' \"tee\": hi", "", "'quoted' \"tee\"", "hi", true},
+		{"backslash in name", `2024-05-01 18:00:10 I chat: 0:-2:back\slash\: \n`, "", `back\slash\`, `\n`, true},
+		{"multibyte name", "2024-05-01 18:00:10 I chat: 0:-2:ネームレス: こんにちは: 世界", "", "ネームレス", "こんにちは: 世界", true},
+		{"first split wins", "2024-05-01 18:00:10 I chat: 0:-2:abc: defghijklmn: hi", "", "abc", "defghijklmn: hi", true},
+		{"name longer than 15 bytes", "2024-05-01 18:00:10 I chat: 0:-2:a very long nickname: hi", "", "a very long nickname", "hi", true},
+		{"empty name", "2024-05-01 18:00:10 I chat: 0:-2:: hi", "", "", "", false},
+		{"empty message", "2024-05-01 18:00:10 I chat: 0:-2:bot: ", "", "", "", false},
+		{"server", "2024-05-01 18:00:10 I chat: -1:-2:: restart: in 5 minutes", "", "", "restart: in 5 minutes", true},
+		{"server message", "2024-05-01 18:00:04 I chat: *** 'nameless tee' changed name to 'OPlayer'", "", "", "", false},
+	}
+
+	for _, tt := range tests {
+		names := func(id int) (string, bool) {
+			return tt.knownName, tt.knownName != ""
+		}
+
+		d, ok := ChatDetailedWithNames(tt.line, names)
+		if ok != tt.ok || d.Name != tt.nick || d.Text != tt.text {
+			t.Errorf("%s: ChatDetailedWithNames(%q) = (%q, %q, %v), want (%q, %q, %v)", tt.name, tt.line, d.Name, d.Text, ok, tt.nick, tt.text, tt.ok)
+		}
+	}
+}
+
+func TestJoinTokenizer(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		nick string
+		clan string
+	}{
+		{"plain", "[5f1c2a3b][game]: id=0 addr=1.2.3.4:8303 version=1796 name='nameless tee' clan='clan' country=-1", "nameless tee", "clan"},
+		{"empty", "[5f1c2a3b][game]: id=0 addr=1.2.3.4:8303 version=1796 name='' clan='' country=-1", "", ""},
+		{"quote in name", "[5f1c2a3b][game]: id=0 addr=1.2.3.4:8303 version=1796 name='it's me' clan='' country=276", "it's me", ""},
+		{"quotes in clan", "[5f1c2a3b][game]: id=0 addr=1.2.3.4:8303 version=1796 name='tee' clan=''q'' country=276", "tee", "'q'"},
+		{"clan separator in name", "[5f1c2a3b][game]: id=0 addr=1.2.3.4:8303 version=1796 name='a' clan='b' clan='c' country=-1", "a", "b' clan='c"},
+		{"fork with long clans", "[5f1c2a3b][game]: id=0 addr=1.2.3.4:8303 version=1796 name='ab' clan='xyz' clan='clan of fourteen' country=-1", "ab' clan='xyz", "clan of fourteen"},
+		{"backslash", `[5f1c2a3b][game]: id=0 addr=1.2.3.4:8303 version=1796 name='\'' clan='\' country=-1`, `\'`, `\`},
+		{"multibyte name of 15 bytes", "[5f1c2a3b][game]: id=0 addr=1.2.3.4:8303 version=1796 name='ネームレス' clan='クラン' country=-1", "ネームレス", "クラン"},
+		{"name of 21 runes", "[5f1c2a3b][game]: id=0 addr=1.2.3.4:8303 version=1796 name='abcdefghijklmnopqrstu' clan='' country=-1", "abcdefghijklmnopqrstu", ""},
+	}
+
+	for _, tt := range tests {
+		d, ok := JoinDetailed(tt.line)
+		if !ok || d.Name != tt.nick || d.Clan != tt.clan {
+			t.Errorf("%s: JoinDetailed(%q) = (%q, %q, %v), want (%q, %q)", tt.name, tt.line, d.Name, d.Clan, ok, tt.nick, tt.clan)
+		}
+	}
+}
+
+func TestTeamJoin(t *testing.T) {
+	id, name, team, ok := TeamJoin("2024-05-01 18:00:03 I game: team_join player='3:a: b' team=0")
+	if !ok || id != 3 || name != "a: b" || team != 0 {
+		t.Errorf("unexpected team join: %d %q %d %v", id, name, team, ok)
+	}
+	id, name, team, ok = TeamJoin("[5f1c2a3b][game]: team_join player='1:nameless tee' m_Team=-1")
+	if !ok || id != 1 || name != "nameless tee" || team != -1 {
+		t.Errorf("unexpected vanilla team join: %d %q %d %v", id, name, team, ok)
+	}
+}
@@ -0,0 +1,231 @@
+package model
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jxsl13/twlog/stringutils"
+)
+
+const (
+	AliasKindNickname = "nickname"
+	AliasKindIP       = "ip"
+)
+
+// AliasNode is either a nickname or an IP address in the alias graph.
+type AliasNode struct {
+	Kind      string    `json:"kind"`
+	Value     string    `json:"value"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	Count     int       `json:"count"`
+}
+
+func (n AliasNode) String() string {
+	return fmt.Sprintf("%s=%q first_seen=%q last_seen=%q count=%d", n.Kind, n.Value, formatTime(n.FirstSeen), formatTime(n.LastSeen), n.Count)
+}
+
+// AliasEdge connects a nickname with an IP address that used it.
+type AliasEdge struct {
+	Nickname  string    `json:"nickname"`
+	IP        string    `json:"ip"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	Count     int       `json:"count"`
+}
+
+func (e AliasEdge) String() string {
+	return fmt.Sprintf("nickname=%q ip=%s first_seen=%q last_seen=%q count=%d", e.Nickname, e.IP, formatTime(e.FirstSeen), formatTime(e.LastSeen), e.Count)
+}
+
+// AliasGraph is a bipartite graph of nicknames and the IP addresses they were used from.
+type AliasGraph struct {
+	Nodes []AliasNode `json:"nodes"`
+	Edges []AliasEdge `json:"edges"`
+
+	// kind + value -> index in Nodes
+	nodeIndex map[aliasKey]int
+	// nickname + ip -> index in Edges
+	edgeIndex map[aliasKey]int
+}
+
+type aliasKey struct {
+	a, b string
+}
+
+func NewAliasGraph() *AliasGraph {
+	return &AliasGraph{
+		Nodes:     make([]AliasNode, 0, 64),
+		Edges:     make([]AliasEdge, 0, 64),
+		nodeIndex: make(map[aliasKey]int, 64),
+		edgeIndex: make(map[aliasKey]int, 64),
+	}
+}
+
+// Observe records that the nickname was used from the IP address at time t.
+func (g *AliasGraph) Observe(nickname, ip string, t time.Time) {
+	nickname = stringutils.VisualizeInvisible(nickname)
+
+	g.observeNode(AliasKindNickname, nickname, t)
+	g.observeNode(AliasKindIP, ip, t)
+
+	key := aliasKey{nickname, ip}
+	idx, ok := g.edgeIndex[key]
+	if !ok {
+		g.Edges = append(g.Edges, AliasEdge{Nickname: nickname, IP: ip})
+		idx = len(g.Edges) - 1
+		g.edgeIndex[key] = idx
+	}
+	e := &g.Edges[idx]
+	e.Count++
+	e.FirstSeen, e.LastSeen = widen(e.FirstSeen, e.LastSeen, t)
+}
+
+func (g *AliasGraph) observeNode(kind, value string, t time.Time) {
+	key := aliasKey{kind, value}
+	idx, ok := g.nodeIndex[key]
+	if !ok {
+		g.Nodes = append(g.Nodes, AliasNode{Kind: kind, Value: value})
+		idx = len(g.Nodes) - 1
+		g.nodeIndex[key] = idx
+	}
+	n := &g.Nodes[idx]
+	n.Count++
+	n.FirstSeen, n.LastSeen = widen(n.FirstSeen, n.LastSeen, t)
+}
+
+// Component returns the connected component that contains all seed nodes.
+// Seed nodes are selected by the seed function, which is called with the kind and value of each node.
+func (g *AliasGraph) Component(seed func(kind, value string) bool) AliasGraph {
+	var (
+		// adjacency lists
+		ipsByNickname = make(map[string][]string, len(g.Nodes))
+		nicknamesByIP = make(map[string][]string, len(g.Nodes))
+
+		visited = make(map[aliasKey]bool, 16)
+		queue   = make([]aliasKey, 0, 16)
+	)
+	for _, e := range g.Edges {
+		ipsByNickname[e.Nickname] = append(ipsByNickname[e.Nickname], e.IP)
+		nicknamesByIP[e.IP] = append(nicknamesByIP[e.IP], e.Nickname)
+	}
+
+	for _, n := range g.Nodes {
+		if seed(n.Kind, n.Value) {
+			key := aliasKey{n.Kind, n.Value}
+			visited[key] = true
+			queue = append(queue, key)
+		}
+	}
+
+	// breadth first search
+	for len(queue) > 0 {
+		key := queue[0]
+		queue = queue[1:]
+
+		var (
+			neighbors []string
+			kind      string
+		)
+		switch key.a {
+		case AliasKindNickname:
+			neighbors, kind = ipsByNickname[key.b], AliasKindIP
+		case AliasKindIP:
+			neighbors, kind = nicknamesByIP[key.b], AliasKindNickname
+		}
+
+		for _, neighbor := range neighbors {
+			next := aliasKey{kind, neighbor}
+			if visited[next] {
+				continue
+			}
+			visited[next] = true
+			queue = append(queue, next)
+		}
+	}
+
+	component := AliasGraph{
+		Nodes: make([]AliasNode, 0, len(visited)),
+		Edges: make([]AliasEdge, 0, len(visited)),
+	}
+	for _, n := range g.Nodes {
+		if visited[aliasKey{n.Kind, n.Value}] {
+			component.Nodes = append(component.Nodes, n)
+		}
+	}
+	for _, e := range g.Edges {
+		if visited[aliasKey{AliasKindNickname, e.Nickname}] {
+			component.Edges = append(component.Edges, e)
+		}
+	}
+	component.sort()
+	return component
+}
+
+func (g *AliasGraph) sort() {
+	slices.SortFunc(g.Nodes, func(a, b AliasNode) int {
+		return cmp.Or(cmp.Compare(a.Kind, b.Kind), cmp.Compare(a.Value, b.Value))
+	})
+	slices.SortFunc(g.Edges, func(a, b AliasEdge) int {
+		return cmp.Or(cmp.Compare(a.Nickname, b.Nickname), cmp.Compare(a.IP, b.IP))
+	})
+}
+
+func (g AliasGraph) String() string {
+	var sb strings.Builder
+	sb.Grow((len(g.Nodes) + len(g.Edges)) * 128)
+	for _, n := range g.Nodes {
+		sb.WriteString(n.String())
+		sb.WriteByte('\n')
+	}
+	for _, e := range g.Edges {
+		sb.WriteString(e.String())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// DOT renders the graph in the Graphviz DOT language.
+func (g AliasGraph) DOT() string {
+	var sb strings.Builder
+	sb.Grow((len(g.Nodes) + len(g.Edges)) * 128)
+	sb.WriteString("graph aliases {\n")
+	for _, n := range g.Nodes {
+		shape := "ellipse"
+		if n.Kind == AliasKindIP {
+			shape = "box"
+		}
+		fmt.Fprintf(&sb, "  %s [label=%s shape=%s];\n", dotID(n.Kind, n.Value), dotQuote(fmt.Sprintf("%s\n%dx", n.Value, n.Count)), shape)
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&sb, "  %s -- %s [label=%s];\n", dotID(AliasKindNickname, e.Nickname), dotID(AliasKindIP, e.IP), dotQuote(fmt.Sprint(e.Count)))
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+func dotID(kind, value string) string {
+	return dotQuote(kind + ":" + value)
+}
+
+func dotQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// widen extends the time span between first and last so that it contains t.
+func widen(first, last, t time.Time) (time.Time, time.Time) {
+	if t.IsZero() {
+		return first, last
+	}
+	if first.IsZero() || t.Before(first) {
+		first = t
+	}
+	if last.IsZero() || t.After(last) {
+		last = t
+	}
+	return first, last
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ContextLine is a raw log line around a search result.
+// Match is true for lines that are search results themselves.
+type ContextLine struct {
+	LineNumber int    `json:"line_number"`
+	Line       string `json:"line"`
+	Match      bool   `json:"match"`
+}
+
+// String formats the line like grep -n, matches are followed by a colon, context lines by a dash.
+func (l ContextLine) String() string {
+	separator := '-'
+	if l.Match {
+		separator = ':'
+	}
+	return fmt.Sprintf("%d%c%s", l.LineNumber, separator, l.Line)
+}
+
+// ContextLines are consecutive raw log lines sorted by their line number.
+type ContextLines []ContextLine
+
+// Overlaps reports whether both windows overlap or are adjacent.
+func (l ContextLines) Overlaps(o ContextLines) bool {
+	if len(l) == 0 || len(o) == 0 {
+		return false
+	}
+	return o[0].LineNumber <= l[len(l)-1].LineNumber+1 && l[0].LineNumber <= o[len(o)-1].LineNumber+1
+}
+
+// Merge returns the union of both windows, lines that are a match in either window are a match.
+func (l ContextLines) Merge(o ContextLines) ContextLines {
+	merged := make(ContextLines, 0, len(l)+len(o))
+	i, j := 0, 0
+	for i < len(l) || j < len(o) {
+		switch {
+		case j >= len(o) || (i < len(l) && l[i].LineNumber < o[j].LineNumber):
+			merged = append(merged, l[i])
+			i++
+		case i >= len(l) || o[j].LineNumber < l[i].LineNumber:
+			merged = append(merged, o[j])
+			j++
+		default:
+			line := l[i]
+			line.Match = line.Match || o[j].Match
+			merged = append(merged, line)
+			i++
+			j++
+		}
+	}
+	return merged
+}
+
+func (l ContextLines) String() string {
+	var sb strings.Builder
+	sb.Grow(len(l) * 128)
+	for _, line := range l {
+		sb.WriteString(line.String())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
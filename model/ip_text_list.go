@@ -1,8 +1,27 @@
 package model
 
+import (
+	"fmt"
+	"strings"
+)
+
 type IPText struct {
 	IP   string `json:"ip"`
 	Text string `json:"text"`
 }
 
+func (i IPText) String() string {
+	return fmt.Sprintf("%s: %s", i.IP, i.Text)
+}
+
 type IPTextList []IPText
+
+func (l IPTextList) String() string {
+	var sb strings.Builder
+	sb.Grow(len(l) * 128)
+	for _, ipText := range l {
+		sb.WriteString(ipText.String())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
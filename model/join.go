@@ -0,0 +1,63 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jxsl13/twlog/stringutils"
+)
+
+// Join is a player joining the server.
+// Depending on the log dialect Port and Version are 0, Nickname and Clan are empty and Country is -1.
+type Join struct {
+	File     string    `json:"file"`
+	Time     time.Time `json:"time"`
+	ID       int       `json:"id"`
+	IP       string    `json:"ip"`
+	Port     int       `json:"port"`
+	Version  int       `json:"version"`
+	Nickname string    `json:"nickname"`
+	Clan     string    `json:"clan"`
+	Country  int       `json:"country"`
+}
+
+func NewJoin(file string, t time.Time, id int, ip string, port, version int, nickname, clan string, country int) Join {
+	return Join{
+		File:     file,
+		Time:     t,
+		ID:       id,
+		IP:       ip,
+		Port:     port,
+		Version:  version,
+		Nickname: stringutils.VisualizeInvisible(nickname),
+		Clan:     stringutils.VisualizeInvisible(clan),
+		Country:  country,
+	}
+}
+
+func (j Join) String() string {
+	return fmt.Sprintf("%s: time=%q id=%d ip=%s port=%d version=%d name=%q clan=%q country=%d",
+		j.File,
+		formatTime(j.Time),
+		j.ID,
+		j.IP,
+		j.Port,
+		j.Version,
+		j.Nickname,
+		j.Clan,
+		j.Country,
+	)
+}
+
+type JoinList []Join
+
+func (l JoinList) String() string {
+	var sb strings.Builder
+	sb.Grow(len(l) * 256)
+	for _, join := range l {
+		sb.WriteString(join.String())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jxsl13/twlog/stringutils"
+)
+
+const (
+	ActionKick  = "kick"
+	ActionBan   = "ban"
+	ActionUnban = "unban"
+)
+
+// Moderation is a kick, ban or unban of a player.
+// By contains the admin or the vote that triggered the action, if known.
+type Moderation struct {
+	File      string    `json:"file"`
+	Time      time.Time `json:"time"`
+	Action    string    `json:"action"`
+	IP        string    `json:"ip"`
+	Nickname  string    `json:"nickname"`
+	Reason    string    `json:"reason"`
+	Duration  Duration  `json:"duration"`
+	Permanent bool      `json:"permanent"`
+	By        string    `json:"by"`
+}
+
+func NewModeration(file string, t time.Time, action, ip, nickname, reason string) Moderation {
+	return Moderation{
+		File:     file,
+		Time:     t,
+		Action:   action,
+		IP:       ip,
+		Nickname: stringutils.VisualizeInvisible(nickname),
+		Reason:   stringutils.VisualizeInvisible(reason),
+	}
+}
+
+func (m Moderation) String() string {
+	duration := m.Duration.String()
+	if m.Permanent {
+		duration = "permanent"
+	}
+
+	switch m.Action {
+	case ActionBan:
+		return fmt.Sprintf("%s: time=%q action=%s ip=%s name=%q duration=%s reason=%q by=%q", m.File, formatTime(m.Time), m.Action, m.IP, m.Nickname, duration, m.Reason, m.By)
+	default:
+		return fmt.Sprintf("%s: time=%q action=%s ip=%s name=%q reason=%q by=%q", m.File, formatTime(m.Time), m.Action, m.IP, m.Nickname, m.Reason, m.By)
+	}
+}
+
+type ModerationList []Moderation
+
+func (l ModerationList) String() string {
+	var sb strings.Builder
+	sb.Grow(len(l) * 256)
+	for _, m := range l {
+		sb.WriteString(m.String())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
@@ -2,21 +2,30 @@ package model
 
 import (
 	"fmt"
+	"time"
 
+	"github.com/jxsl13/twlog/match"
 	"github.com/jxsl13/twlog/stringutils"
 )
 
 type PlayerExtended struct {
-	File     string `json:"file"`
-	Nickname string `json:"nickname"`
-	ID       int    `json:"id"`
-	IP       string `json:"ip"`
-	Text     string `json:"text"`
+	File     string    `json:"file"`
+	Time     time.Time `json:"time"`
+	Nickname string    `json:"nickname"`
+	ID       int       `json:"id"`
+	IP       string    `json:"ip"`
+	Text     string    `json:"text"`
+	Pattern  string    `json:"pattern,omitempty" csv:",omitempty"` // search pattern that matched the text or nickname, only set for several patterns
+	// Context contains the surrounding log lines in case context lines were requested.
+	// Players whose context lines overlap share the merged lines.
+	// It is a pointer in order to keep the player comparable.
+	Context *ContextLines `json:"context,omitempty" csv:"-"`
 }
 
-func NewPlayerExtended(file, nickname string, id int, ip, text string) PlayerExtended {
+func NewPlayerExtended(file string, t time.Time, nickname string, id int, ip, text string) PlayerExtended {
 	return PlayerExtended{
 		File:     file,
+		Time:     t,
 		Nickname: stringutils.VisualizeInvisible(nickname),
 		ID:       id,
 		IP:       ip,
@@ -25,5 +34,21 @@ func NewPlayerExtended(file, nickname string, id int, ip, text string) PlayerExt
 }
 
 func (p PlayerExtended) String() string {
-	return fmt.Sprintf("%s: id=%d ip=%s name=%s text=%s", p.File, p.ID, p.IP, p.Nickname, p.Text)
+	pattern := ""
+	if p.Pattern != "" {
+		pattern = fmt.Sprintf(" pattern=%q", p.Pattern)
+	}
+	if p.Time.IsZero() {
+		return fmt.Sprintf("%s: id=%d ip=%s name=%s%s text=%s", p.File, p.ID, p.IP, p.Nickname, pattern, p.Text)
+	}
+	return fmt.Sprintf("%s: time=%q id=%d ip=%s name=%s%s text=%s", p.File, p.Time.Format(match.TimestampLayout), p.ID, p.IP, p.Nickname, pattern, p.Text)
+}
+
+// ToPlayer drops the file, time, id and matched pattern of the player.
+func (p PlayerExtended) ToPlayer() Player {
+	return Player{
+		Nickname: p.Nickname,
+		IP:       p.IP,
+		Text:     p.Text,
+	}
 }
@@ -4,12 +4,42 @@ import "strings"
 
 type PlayerExtendedList []PlayerExtended
 
+// String prints one player per line.
+// Context lines are printed after the players they belong to, overlapping windows of
+// consecutive players of the same file are merged and groups of players are separated by --.
 func (p PlayerExtendedList) String() string {
-	var sb strings.Builder
+	var (
+		sb       strings.Builder
+		window   ContextLines
+		newGroup = true
+		printed  = false
+	)
 	sb.Grow(len(p) * 512)
-	for _, player := range p {
+
+	for idx, player := range p {
+		if player.Context != nil && newGroup && printed {
+			sb.WriteString("--\n")
+		}
 		sb.WriteString(player.String())
 		sb.WriteByte('\n')
+
+		if player.Context == nil {
+			continue
+		}
+		window = window.Merge(*player.Context)
+
+		if idx+1 < len(p) {
+			next := p[idx+1]
+			if next.File == player.File && next.Context != nil && window.Overlaps(*next.Context) {
+				newGroup = false
+				continue
+			}
+		}
+
+		sb.WriteString(window.String())
+		window = nil
+		newGroup = true
+		printed = true
 	}
 	return sb.String()
 }
@@ -17,11 +47,7 @@ func (p PlayerExtendedList) String() string {
 func (p PlayerExtendedList) ToPlayerList() PlayerList {
 	players := make([]Player, 0, len(p))
 	for _, player := range p {
-		players = append(players, Player{
-			Nickname: player.Nickname,
-			IP:       player.IP,
-			Text:     player.Text,
-		})
+		players = append(players, player.ToPlayer())
 	}
 	return players
 }
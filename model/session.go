@@ -0,0 +1,86 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jxsl13/twlog/stringutils"
+)
+
+// Session is the time span between a player joining and leaving a server.
+// The leave time is unknown in case the log file ends before the player left.
+type Session struct {
+	File        string    `json:"file"`
+	ID          int       `json:"id"`
+	IP          string    `json:"ip"`
+	Nicknames   []string  `json:"nicknames"`
+	JoinTime    time.Time `json:"join_time"`
+	LeaveTime   time.Time `json:"leave_time"`
+	LeaveReason string    `json:"leave_reason"`
+	Duration    Duration  `json:"duration"`
+}
+
+func NewSession(file string, id int, ip string, joinTime time.Time) *Session {
+	return &Session{
+		File:      file,
+		ID:        id,
+		IP:        ip,
+		Nicknames: make([]string, 0, 1),
+		JoinTime:  joinTime,
+	}
+}
+
+// AddNickname adds a nickname that the player used during the session.
+func (s *Session) AddNickname(nickname string) {
+	nickname = stringutils.VisualizeInvisible(nickname)
+	for _, n := range s.Nicknames {
+		if n == nickname {
+			return
+		}
+	}
+	s.Nicknames = append(s.Nicknames, nickname)
+}
+
+// Nickname returns the most recently used nickname.
+func (s *Session) Nickname() string {
+	if len(s.Nicknames) == 0 {
+		return ""
+	}
+	return s.Nicknames[len(s.Nicknames)-1]
+}
+
+// Close ends the session.
+// leaveTime may be zero in case the session ended without a leave line.
+func (s *Session) Close(leaveTime time.Time, reason string) {
+	s.LeaveTime = leaveTime
+	s.LeaveReason = reason
+	if !s.JoinTime.IsZero() && !leaveTime.IsZero() {
+		s.Duration = Duration(leaveTime.Sub(s.JoinTime))
+	}
+}
+
+func (s Session) String() string {
+	return fmt.Sprintf("%s: id=%d ip=%s names=%q join=%q leave=%q duration=%s reason=%q",
+		s.File,
+		s.ID,
+		s.IP,
+		strings.Join(s.Nicknames, ","),
+		formatTime(s.JoinTime),
+		formatTime(s.LeaveTime),
+		s.Duration,
+		s.LeaveReason,
+	)
+}
+
+type SessionList []Session
+
+func (l SessionList) String() string {
+	var sb strings.Builder
+	sb.Grow(len(l) * 256)
+	for _, session := range l {
+		sb.WriteString(session.String())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
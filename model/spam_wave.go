@@ -0,0 +1,57 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SpamWave is a chat message that was sent from many different IPs within a short time window.
+// Phrase is the normalized text of the first message and Regex matches all messages of the wave.
+type SpamWave struct {
+	Phrase    string    `json:"phrase"`
+	Regex     string    `json:"regex"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Messages  int       `json:"messages"`
+	IPs       []string  `json:"ips"`
+	Nicknames []string  `json:"nicknames"`
+	Files     []string  `json:"files"`
+}
+
+func (w SpamWave) String() string {
+	// the regex is not quoted in order to be copied as is
+	return fmt.Sprintf("start=%q end=%q messages=%d ips=%d phrase=%q\n\tregex: %s\n\tips: %s\n\tnames: %q",
+		formatTime(w.Start),
+		formatTime(w.End),
+		w.Messages,
+		len(w.IPs),
+		w.Phrase,
+		w.Regex,
+		strings.Join(w.IPs, ","),
+		strings.Join(w.Nicknames, ","),
+	)
+}
+
+type SpamWaveList []SpamWave
+
+func (l SpamWaveList) String() string {
+	var sb strings.Builder
+	sb.Grow(len(l) * 512)
+	for _, w := range l {
+		sb.WriteString(w.String())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// ToIPTextList returns every IP of every wave together with the phrase of the wave.
+func (l SpamWaveList) ToIPTextList() IPTextList {
+	ipTextList := make(IPTextList, 0, len(l)*4)
+	for _, w := range l {
+		for _, ip := range w.IPs {
+			ipTextList = append(ipTextList, IPText{IP: ip, Text: w.Phrase})
+		}
+	}
+	return ipTextList
+}
@@ -0,0 +1,76 @@
+package model
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	StatTotal        = "total"
+	StatChatters     = "chatters"
+	StatIPs          = "ips"
+	StatNicknameIPs  = "nickname_ips"
+	StatPhrases      = "phrases"
+	StatHours        = "hours"
+	StatFileMessages = "file_messages"
+	StatFileJoins    = "file_joins"
+)
+
+// Stat is a single counter of a statistic, e.g. the number of chat messages of a player.
+// Rank starts at 1 for the largest count of a statistic.
+type Stat struct {
+	Statistic string `json:"statistic"`
+	Rank      int    `json:"rank"`
+	Key       string `json:"key"`
+	Count     int    `json:"count"`
+}
+
+func (s Stat) String() string {
+	return fmt.Sprintf("%s #%d: %q=%d", s.Statistic, s.Rank, s.Key, s.Count)
+}
+
+type StatList []Stat
+
+// NewStatList returns the top counts in descending order, ties are sorted by key.
+// top <= 0 returns all counts.
+func NewStatList(statistic string, counts map[string]int, top int) StatList {
+	l := make(StatList, 0, len(counts))
+	for key, count := range counts {
+		l = append(l, Stat{
+			Statistic: statistic,
+			Key:       key,
+			Count:     count,
+		})
+	}
+
+	slices.SortFunc(l, func(a, b Stat) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	if top > 0 && len(l) > top {
+		l = l[:top]
+	}
+	for i := range l {
+		l[i].Rank = i + 1
+	}
+	return l
+}
+
+func (l StatList) String() string {
+	var sb strings.Builder
+	sb.Grow(len(l) * 64)
+	for idx, s := range l {
+		if idx > 0 && s.Statistic != l[idx-1].Statistic {
+			// separate statistics
+			sb.WriteByte('\n')
+		}
+		sb.WriteString(s.String())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
@@ -0,0 +1,16 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jxsl13/twlog/match"
+)
+
+// formatTime formats t in the same layout DDNet uses for its log timestamps.
+// Unknown times are printed as a dash.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(match.TimestampLayout)
+}